@@ -1,5 +1,7 @@
 package garbagecollector
 
+import "go-playbook/basic/04-standard-collections/detach"
+
 // Context: Object Retention via Subslicing
 // You are building an image processing pipeline. A worker reads a 5MB image,
 // extracts only the 64-byte EXIF metadata header, and stores the metadata
@@ -15,20 +17,17 @@ package garbagecollector
 // 1. Refactor `ExtractMetadata` to return a completely independent byte slice.
 // 2. You must allocate a new slice of the exact required length (64).
 // 3. You must completely `copy()` the data into the new slice.
+//
+// `detach.Extract` does the allocate-and-copy for us, sized to exactly the ranges we keep.
 
 var MetadataCache [][]byte
 
 func ExtractMetadata(massiveImage []byte) {
-	if len(massiveImage) < 64 {
-		return
+	parts, err := detach.Extract(massiveImage, detach.Range{Start: 0, End: 64})
+	if err != nil {
+		return // Image shorter than the metadata header.
 	}
 
-	// BUG: This subslice retains the ENTIRE underlying array of `massiveImage`.
-	// TODO: Create a new slice `metadata` of length 64, and `copy` the first
-	// 64 bytes of `massiveImage` into it. Then store `metadata`.
-
-	metadata := massiveImage[:64]
-
 	// Store it in the cache for the lifetime of the application
-	MetadataCache = append(MetadataCache, metadata)
+	MetadataCache = append(MetadataCache, parts[0])
 }
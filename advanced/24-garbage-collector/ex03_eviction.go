@@ -1,8 +1,6 @@
 package garbagecollector
 
-import (
-	"sync"
-)
+import "go-playbook/basic/04-standard-collections/sessioncache"
 
 // Context: Unbounded Caches and OOM
 // You are building a session store. Whenever a user logs in, you save their
@@ -26,33 +24,24 @@ type User struct {
 	Data [1024]byte // 1KB of data per user
 }
 
-type SessionCache struct {
-	mu    sync.Mutex
-	store map[string]User
+// MaxSessions bounds the session store. The shared `sessioncache` package (chapter 04)
+// evicts the least recently used session once it is reached.
+const MaxSessions = 1000
 
-	// TODO: Add MaxKeys to enforce a cap
+type SessionCache struct {
+	cache *sessioncache.Cache[string, User]
 }
 
 func NewSessionCache() *SessionCache {
 	return &SessionCache{
-		store: make(map[string]User),
+		cache: sessioncache.New(sessioncache.Options[string, User]{MaxEntries: MaxSessions}),
 	}
 }
 
 func (c *SessionCache) Set(id string, u User) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// BUG: Unbounded growth!
-	// TODO: If len >= MaxKeys, evict something before inserting!
-
-	c.store[id] = u
+	c.cache.Set(id, u)
 }
 
 func (c *SessionCache) Get(id string) (User, bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	u, ok := c.store[id]
-	return u, ok
+	return c.cache.Get(id)
 }
@@ -0,0 +1,198 @@
+// Package checked provides integer arithmetic that reports overflow instead of
+// wrapping around.
+//
+// Go conversions and arithmetic never panic on overflow: uint32(v) drops the high
+// bits and a * b wraps, and the result is just another plausible-looking number.
+// Every operation here either returns the exact result or ErrOverflow (or
+// ErrDivideByZero). The Saturating* variants clamp to the type's bounds instead, for
+// metrics-style code where a pinned value beats an error.
+//
+// MulDiv performs a * b / d with a 128-bit intermediate, so it only fails when the
+// final quotient doesn't fit, never because of the intermediate product:
+//
+//	fee, err := checked.MulDiv(amount, rateNumerator, rateDenominator)
+package checked
+
+import (
+	"errors"
+	"math/bits"
+	"unsafe"
+)
+
+var (
+	ErrOverflow     = errors.New("integer overflow")
+	ErrDivideByZero = errors.New("integer divide by zero")
+)
+
+// Integer is any built-in integer type (or a type defined on one).
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+func isSigned[T Integer]() bool {
+	return ^T(0) < 0
+}
+
+func bitSize[T Integer]() uint {
+	var zero T
+	return uint(unsafe.Sizeof(zero)) * 8
+}
+
+// MaxOf returns the largest value representable by T.
+func MaxOf[T Integer]() T {
+	if isSigned[T]() {
+		return T(1)<<(bitSize[T]()-1) - 1
+	}
+	return ^T(0)
+}
+
+// MinOf returns the smallest value representable by T.
+func MinOf[T Integer]() T {
+	if isSigned[T]() {
+		return T(1) << (bitSize[T]() - 1)
+	}
+	return 0
+}
+
+// Convert converts v to To, returning ErrOverflow if the value doesn't fit.
+func Convert[To, From Integer](v From) (To, error) {
+	t := To(v)
+	// A conversion is lossless iff it round-trips and preserves the sign.
+	if From(t) != v || (t < 0) != (v < 0) {
+		return 0, ErrOverflow
+	}
+	return t, nil
+}
+
+// SaturatingConvert converts v to To, clamping to To's bounds instead of failing.
+func SaturatingConvert[To, From Integer](v From) To {
+	t, err := Convert[To](v)
+	if err == nil {
+		return t
+	}
+	if v < 0 {
+		return MinOf[To]()
+	}
+	return MaxOf[To]()
+}
+
+// Add returns a + b or ErrOverflow.
+func Add[T Integer](a, b T) (T, error) {
+	s := a + b
+	if (b >= 0 && s < a) || (b < 0 && s > a) {
+		return 0, ErrOverflow
+	}
+	return s, nil
+}
+
+// Sub returns a - b or ErrOverflow.
+func Sub[T Integer](a, b T) (T, error) {
+	d := a - b
+	if (b >= 0 && d > a) || (b < 0 && d < a) {
+		return 0, ErrOverflow
+	}
+	return d, nil
+}
+
+// Mul returns a * b or ErrOverflow.
+func Mul[T Integer](a, b T) (T, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	if isSigned[T]() {
+		// MinOf * -1 wraps back to MinOf, which the division check below can't see.
+		// ^T(0) is -1 for signed types; a -1 literal doesn't compile against unsigned ones.
+		minusOne, minVal := ^T(0), MinOf[T]()
+		if (a == minusOne && b == minVal) || (b == minusOne && a == minVal) {
+			return 0, ErrOverflow
+		}
+	}
+	p := a * b
+	if p/b != a {
+		return 0, ErrOverflow
+	}
+	return p, nil
+}
+
+// Div returns a / b (truncated toward zero), ErrDivideByZero or ErrOverflow.
+func Div[T Integer](a, b T) (T, error) {
+	if b == 0 {
+		return 0, ErrDivideByZero
+	}
+	if isSigned[T]() && b == ^T(0) && a == MinOf[T]() {
+		return 0, ErrOverflow
+	}
+	return a / b, nil
+}
+
+// saturate picks the bound an overflowing operation was heading towards.
+func saturate[T Integer](negative bool) T {
+	if negative {
+		return MinOf[T]()
+	}
+	return MaxOf[T]()
+}
+
+// SaturatingAdd returns a + b clamped to T's bounds.
+func SaturatingAdd[T Integer](a, b T) T {
+	s, err := Add(a, b)
+	if err != nil {
+		return saturate[T](b < 0)
+	}
+	return s
+}
+
+// SaturatingSub returns a - b clamped to T's bounds.
+func SaturatingSub[T Integer](a, b T) T {
+	d, err := Sub(a, b)
+	if err != nil {
+		return saturate[T](b >= 0)
+	}
+	return d
+}
+
+// SaturatingMul returns a * b clamped to T's bounds.
+func SaturatingMul[T Integer](a, b T) T {
+	p, err := Mul(a, b)
+	if err != nil {
+		return saturate[T]((a < 0) != (b < 0))
+	}
+	return p
+}
+
+// magnitude splits v into its sign and absolute value without overflowing on MinOf.
+func magnitude[T Integer](v T) (negative bool, abs uint64) {
+	if v < 0 {
+		// -int64(MinInt64) wraps to MinInt64, whose uint64 bit pattern is exactly 2^63.
+		return true, uint64(-int64(v))
+	}
+	return false, uint64(v)
+}
+
+// MulDiv returns a * b / d (truncated toward zero) using a 128-bit intermediate
+// product. It fails only if d is zero or the final quotient does not fit in T.
+func MulDiv[T Integer](a, b, d T) (T, error) {
+	if d == 0 {
+		return 0, ErrDivideByZero
+	}
+
+	na, ma := magnitude(a)
+	nb, mb := magnitude(b)
+	nd, md := magnitude(d)
+
+	hi, lo := bits.Mul64(ma, mb)
+	if hi >= md {
+		// The quotient needs more than 64 bits; no Go integer can hold it.
+		return 0, ErrOverflow
+	}
+	q, _ := bits.Div64(hi, lo, md)
+
+	if na != nb != nd && q != 0 {
+		if q > 1<<63 {
+			return 0, ErrOverflow
+		}
+		return Convert[T](-int64(q))
+	}
+	return Convert[T](q)
+}
@@ -0,0 +1,172 @@
+package checked
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	if v, err := Convert[uint32](int64(math.MaxUint32)); err != nil || v != math.MaxUint32 {
+		t.Fatalf("Convert[uint32](MaxUint32) = %v, %v", v, err)
+	}
+	if _, err := Convert[uint32](int64(math.MaxUint32 + 1)); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("Expected ErrOverflow converting MaxUint32+1, got %v", err)
+	}
+	if _, err := Convert[uint64](int8(-1)); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("Expected ErrOverflow converting -1 to uint64, got %v", err)
+	}
+	if _, err := Convert[int64](uint64(math.MaxUint64)); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("Expected ErrOverflow converting MaxUint64 to int64, got %v", err)
+	}
+	if v, err := Convert[int8](int64(-128)); err != nil || v != -128 {
+		t.Fatalf("Convert[int8](-128) = %v, %v", v, err)
+	}
+
+	// Exhaustively compare against the exact answer for every int16 -> int8/uint8 conversion.
+	for i := math.MinInt16; i <= math.MaxInt16; i++ {
+		v := int16(i)
+		_, err := Convert[int8](v)
+		if fits := i >= math.MinInt8 && i <= math.MaxInt8; fits != (err == nil) {
+			t.Fatalf("Convert[int8](%d): fits=%v err=%v", i, fits, err)
+		}
+		_, err = Convert[uint8](v)
+		if fits := i >= 0 && i <= math.MaxUint8; fits != (err == nil) {
+			t.Fatalf("Convert[uint8](%d): fits=%v err=%v", i, fits, err)
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	if MaxOf[int64]() != math.MaxInt64 || MinOf[int64]() != math.MinInt64 {
+		t.Errorf("int64 bounds wrong: %d..%d", MinOf[int64](), MaxOf[int64]())
+	}
+	if MaxOf[uint16]() != math.MaxUint16 || MinOf[uint16]() != 0 {
+		t.Errorf("uint16 bounds wrong: %d..%d", MinOf[uint16](), MaxOf[uint16]())
+	}
+	if MaxOf[int8]() != math.MaxInt8 || MinOf[int8]() != math.MinInt8 {
+		t.Errorf("int8 bounds wrong: %d..%d", MinOf[int8](), MaxOf[int8]())
+	}
+}
+
+// checkOp runs op over every int8 pair and compares it with the exact result in int.
+func checkOp(t *testing.T, name string, op func(a, b int8) (int8, error), exact func(a, b int) (int, bool)) {
+	t.Helper()
+	for a := math.MinInt8; a <= math.MaxInt8; a++ {
+		for b := math.MinInt8; b <= math.MaxInt8; b++ {
+			want, defined := exact(a, b)
+			got, err := op(int8(a), int8(b))
+			switch {
+			case !defined:
+				if err == nil {
+					t.Fatalf("%s(%d, %d): expected an error, got %d", name, a, b, got)
+				}
+			case want < math.MinInt8 || want > math.MaxInt8:
+				if !errors.Is(err, ErrOverflow) {
+					t.Fatalf("%s(%d, %d): expected ErrOverflow, got %d, %v", name, a, b, got, err)
+				}
+			default:
+				if err != nil || int(got) != want {
+					t.Fatalf("%s(%d, %d) = %d, %v; want %d", name, a, b, got, err, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckedArithmeticExhaustive(t *testing.T) {
+	checkOp(t, "Add", Add[int8], func(a, b int) (int, bool) { return a + b, true })
+	checkOp(t, "Sub", Sub[int8], func(a, b int) (int, bool) { return a - b, true })
+	checkOp(t, "Mul", Mul[int8], func(a, b int) (int, bool) { return a * b, true })
+	checkOp(t, "Div", Div[int8], func(a, b int) (int, bool) {
+		if b == 0 {
+			return 0, false
+		}
+		return a / b, true
+	})
+}
+
+func TestCheckedArithmeticUnsigned(t *testing.T) {
+	if _, err := Sub[uint](1, 2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Sub[uint](1, 2): expected ErrOverflow, got %v", err)
+	}
+	if _, err := Add[uint64](math.MaxUint64, 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Add[uint64](Max, 1): expected ErrOverflow, got %v", err)
+	}
+	if _, err := Mul[uint32](1<<16, 1<<16); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Mul[uint32](2^16, 2^16): expected ErrOverflow, got %v", err)
+	}
+	if _, err := Div[uint8](1, 0); !errors.Is(err, ErrDivideByZero) {
+		t.Errorf("Div[uint8](1, 0): expected ErrDivideByZero, got %v", err)
+	}
+}
+
+func TestSaturating(t *testing.T) {
+	tests := []struct {
+		name string
+		got  int64
+		want int64
+	}{
+		{"Add clamps up", SaturatingAdd[int64](math.MaxInt64, 1), math.MaxInt64},
+		{"Add clamps down", SaturatingAdd[int64](math.MinInt64, -1), math.MinInt64},
+		{"Sub clamps down", SaturatingSub[int64](math.MinInt64, 1), math.MinInt64},
+		{"Sub clamps up", SaturatingSub[int64](math.MaxInt64, -1), math.MaxInt64},
+		{"Mul clamps up", SaturatingMul[int64](math.MinInt64, -1), math.MaxInt64},
+		{"Mul clamps down", SaturatingMul[int64](math.MaxInt64, -2), math.MinInt64},
+		{"In range is exact", SaturatingMul[int64](-3, 7), -21},
+		{"Convert clamps", int64(SaturatingConvert[int8](int64(1000))), math.MaxInt8},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.got != tc.want {
+				t.Fatalf("got %d, want %d", tc.got, tc.want)
+			}
+		})
+	}
+
+	if got := SaturatingSub[uint8](3, 5); got != 0 {
+		t.Fatalf("SaturatingSub[uint8](3, 5) = %d, want 0", got)
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b, d int64
+		want    int64
+		wantErr error
+	}{
+		// The exact scaling from ex03_constants.go: the product needs ~123 bits.
+		{"Scale without intermediate overflow", 1_000_000_000_000_000_000, 5_000_000_000_000_000_000 / 10, 1_000_000_000_000_000_000, 500_000_000_000_000_000, nil},
+		{"Negative operand", -7, 3, 2, -10, nil},
+		{"Two negatives", -7, -3, 2, 10, nil},
+		{"Negative divisor", 7, 3, -2, -10, nil},
+		{"MinInt64 exact", math.MinInt64, 1, 1, math.MinInt64, nil},
+		{"MinInt64 negated overflows", math.MinInt64, -1, 1, 0, ErrOverflow},
+		{"Quotient too large", math.MaxInt64, math.MaxInt64, 1, 0, ErrOverflow},
+		{"Divide by zero", 1, 1, 0, 0, ErrDivideByZero},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := MulDiv(tc.a, tc.b, tc.d)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("Expected %v, got %d, %v", tc.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("MulDiv(%d, %d, %d) = %d, %v; want %d", tc.a, tc.b, tc.d, got, err, tc.want)
+			}
+		})
+	}
+
+	if got, err := MulDiv[uint64](math.MaxUint64, math.MaxUint64, math.MaxUint64); err != nil || got != math.MaxUint64 {
+		t.Fatalf("MulDiv[uint64](Max, Max, Max) = %d, %v", got, err)
+	}
+	if _, err := MulDiv[uint8](200, 200, 100); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("MulDiv[uint8](200, 200, 100): expected ErrOverflow, got %v", err)
+	}
+}
@@ -25,20 +25,17 @@ type UserSettings struct {
 	Retries       int
 }
 
-type OptionalBool struct {
-	Value bool
-	Valid bool // True if explicitly set
-}
+// OptionalBool and OptionalInt are instantiations of the generic Optional[T]
+// (see ex05_optional_patch.go), so patches built from them also work with PatchStruct.
+type OptionalBool = Optional[bool]
 
-type OptionalInt struct {
-	Value int
-	Valid bool // True if explicitly set
-}
+type OptionalInt = Optional[int]
 
 // UserSettingsPatch uses value wrappers capable of distinguishing zero values from unset fields.
+// The tags let real PATCH payloads decode into it (see ex06_optional_encoding.go).
 type UserSettingsPatch struct {
-	Notifications OptionalBool
-	Retries       OptionalInt
+	Notifications OptionalBool `json:"notifications,omitzero"`
+	Retries       OptionalInt  `json:"retries,omitzero"`
 }
 
 // ApplyPatch applies valid fields from patch to base settings.
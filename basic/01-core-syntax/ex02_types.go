@@ -17,15 +17,14 @@ package core
 // Note: When you fix requirement 1, your code might initially fail to compile due to strict typing.
 // Fix the compilation errors by applying correct type conversions.
 
-// TODO: Change these from type aliases to defined types.
-type UserID = string
-type ProductID = string
+// UserID and ProductID are defined types, so they can't be swapped for each other or
+// for a bare string. Parsing, generation and encoding live in ex07_typed_ids.go.
+type UserID string
+type ProductID string
 
 // ProcessOrder matches a user and product.
 func ProcessOrder(u UserID, p ProductID) string {
-	// TODO: implement logic that returns formatted string: "Order: <user> bought <product>"
-	// Ensure you are safely coercing them back to strings if needed for formatting.
-	return "Order: " + string(u) + " bought " + string(p) // string cast works for both aliases and defined
+	return "Order: " + u.String() + " bought " + p.String()
 }
 
 // LegacyFetch fetches from DB using string ID. Do not change this function's signature.
@@ -35,7 +34,6 @@ func LegacyFetch(id string) string {
 
 // FetchUser fetches user data. It must use LegacyFetch internally, doing correct conversion.
 func FetchUser(u UserID) string {
-	// TODO: If you changed UserID to a defined type, passing `u` directly to LegacyFetch
-	// will fail to compile. Fix the conversion!
-	return LegacyFetch(u)
+	// The legacy driver only understands raw strings: convert explicitly at the boundary.
+	return LegacyFetch(string(u))
 }
@@ -1,5 +1,7 @@
 package core
 
+import "go-playbook/basic/01-core-syntax/checked"
+
 // Context: Untyped Constants Behavior
 //
 // You are writing a precise rate calculation module for a crypto-exchange.
@@ -22,8 +24,11 @@ const Multiplier int64 = 5000000000000000000
 const Divisor int64 = 1000000000000000000
 
 func ComputeScale() int64 {
-	// TODO: Return (Base * Multiplier) / Divisor
-	// The test expects exactly 5000000000000000000.
-	// You might see compilation overflow errors until you fix the consts.
-	return 0
+	// Base * Multiplier overflows int64, but the quotient fits. MulDiv keeps the
+	// intermediate product in 128 bits, so the typed constants can stay typed.
+	scale, err := checked.MulDiv(Base, Multiplier, Divisor)
+	if err != nil {
+		panic(err) // Unreachable: the inputs are constants whose quotient fits.
+	}
+	return scale
 }
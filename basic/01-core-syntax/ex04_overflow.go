@@ -1,6 +1,6 @@
 package core
 
-import "errors"
+import "go-playbook/basic/01-core-syntax/checked"
 
 // Context: Subtle integer overflows.
 // We are parsing 64-bit integer timestamps or counters from a modern gRPC stream,
@@ -16,11 +16,11 @@ import "errors"
 //    return a sentinel error `ErrOverflow`.
 // 3. Otherwise return the converted value.
 
-var ErrOverflow = errors.New("integer overflow")
+// ErrOverflow is checked.ErrOverflow, so errors from either package compare equal.
+var ErrOverflow = checked.ErrOverflow
 
 func SafeConvertInt64ToUint32(val int64) (uint32, error) {
-	// TODO: Catch overflow/underflow, returning ErrOverflow when appropriate.
-	// Otherwise, return the safely casted uint32.
-
-	return uint32(val), nil // BUG: Silently truncates today.
+	// Convert round-trips the value and checks the sign, so it catches both
+	// truncation and negative inputs.
+	return checked.Convert[uint32](val)
 }
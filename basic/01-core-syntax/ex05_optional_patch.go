@@ -0,0 +1,176 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Context: Generic Optional Fields and a Patch Engine
+// The settings service owns a dozen entity types, and every one of them needs the
+// same "only override what the client actually sent" merge that `ApplyPatch` does
+// for `UserSettings`. Copy-pasting that merge per entity is exactly how the
+// accidental-zero-override bug keeps coming back.
+//
+// Why this matters: `OptionalBool` and `OptionalInt` are the same struct written twice.
+// A single `Optional[T]` captures "value + presence" for any type, and because every
+// instantiation shares one shape, a small reflection-based engine can merge ANY patch
+// struct made of `Optional[T]` fields onto its matching base struct.
+//
+// Design:
+// 1. Patch fields are matched to base fields by name. The `patch:"Name"` tag renames
+//    the target and `patch:"-"` skips the field entirely.
+// 2. Every patch field must be an `Optional[T]` whose `T` is assignable to the base
+//    field. Anything else is a programming error and is reported as `ErrPatchMismatch`
+//    rather than silently ignored.
+// 3. A null field (`Null[T]()`) resets the base field to its zero value.
+// 4. The engine never mutates its input: it returns a new value plus the list of
+//    fields whose value actually changed.
+
+var ErrPatchMismatch = errors.New("patch does not match base struct")
+
+// Optional holds a value together with a flag recording whether it was explicitly set.
+// An explicit null (see ex06_optional_encoding.go) is Valid with Null set and a zero Value.
+type Optional[T any] struct {
+	Value T
+	Valid bool // True if explicitly set
+	Null  bool // True if explicitly set to null, i.e. "reset to default"
+}
+
+// Some returns an Optional explicitly set to v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{Value: v, Valid: true}
+}
+
+// Null returns an Optional explicitly set to null.
+func Null[T any]() Optional[T] {
+	return Optional[T]{Valid: true, Null: true}
+}
+
+// Get returns the value and whether a concrete (non-null) value was explicitly set.
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.Valid && !o.Null
+}
+
+// OrElse returns the value if a concrete value was set, otherwise def.
+func (o Optional[T]) OrElse(def T) T {
+	if o.Valid && !o.Null {
+		return o.Value
+	}
+	return def
+}
+
+// patchField is implemented by every Optional instantiation so the engine can read
+// the wrapped value without knowing T.
+type patchField interface {
+	patchValue() (reflect.Value, bool)
+}
+
+func (o Optional[T]) patchValue() (reflect.Value, bool) {
+	if o.Null {
+		// An explicit null resets the base field to its zero value.
+		var zero T
+		return reflect.ValueOf(&zero).Elem(), true
+	}
+	// Going through a pointer keeps interface-typed T values (including nil) addressable.
+	return reflect.ValueOf(&o.Value).Elem(), o.Valid
+}
+
+var patchFieldType = reflect.TypeFor[patchField]()
+
+// FieldChange describes a single field whose value differs between two structs.
+type FieldChange struct {
+	Field string
+	Old   any
+	New   any
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Field, c.Old, c.New)
+}
+
+// PatchStruct applies every set field of patch onto a copy of base and returns the
+// result together with the fields that actually changed. base must be a struct and
+// patch a struct made only of Optional[T] fields.
+func PatchStruct[T, P any](base T, patch P) (T, []FieldChange, error) {
+	out := base
+
+	bv := reflect.ValueOf(&out).Elem()
+	pv := reflect.ValueOf(patch)
+	if bv.Kind() != reflect.Struct {
+		return base, nil, fmt.Errorf("%w: base is %s, not a struct", ErrPatchMismatch, bv.Type())
+	}
+	if pv.Kind() != reflect.Struct {
+		return base, nil, fmt.Errorf("%w: patch is %T, not a struct", ErrPatchMismatch, patch)
+	}
+
+	pt := pv.Type()
+	for i := 0; i < pt.NumField(); i++ {
+		sf := pt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		target := sf.Name
+		if tag, ok := sf.Tag.Lookup("patch"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				target = tag
+			}
+		}
+
+		if !sf.Type.Implements(patchFieldType) {
+			return base, nil, fmt.Errorf("%w: patch field %q is %s, not an Optional", ErrPatchMismatch, sf.Name, sf.Type)
+		}
+
+		dst := bv.FieldByName(target)
+		if !dst.IsValid() || !dst.CanSet() {
+			return base, nil, fmt.Errorf("%w: base %s has no settable field %q", ErrPatchMismatch, bv.Type(), target)
+		}
+
+		val, set := pv.Field(i).Interface().(patchField).patchValue()
+		if !val.Type().AssignableTo(dst.Type()) {
+			return base, nil, fmt.Errorf("%w: field %q expects %s, patch carries %s", ErrPatchMismatch, target, dst.Type(), val.Type())
+		}
+		if set {
+			dst.Set(val)
+		}
+	}
+
+	return out, Diff(base, out), nil
+}
+
+// Diff compares the exported fields of two structs of the same type and returns the
+// ones that differ, in declaration order. Non-struct values yield no changes.
+func Diff[T any](before, after T) []FieldChange {
+	bv := reflect.ValueOf(&before).Elem()
+	av := reflect.ValueOf(&after).Elem()
+	if bv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var changes []FieldChange
+	t := bv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		oldVal := bv.Field(i).Interface()
+		newVal := av.Field(i).Interface()
+		if !reflect.DeepEqual(oldVal, newVal) {
+			changes = append(changes, FieldChange{Field: t.Field(i).Name, Old: oldVal, New: newVal})
+		}
+	}
+	return changes
+}
+
+// ChangedFields returns just the names of the changed fields.
+func ChangedFields(changes []FieldChange) []string {
+	names := make([]string, 0, len(changes))
+	for _, c := range changes {
+		names = append(names, c.Field)
+	}
+	return names
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type Profile struct {
+	Name     string
+	Age      int
+	Tags     []string
+	Verified bool
+}
+
+type ProfilePatch struct {
+	Name        Optional[string]
+	Age         Optional[int]
+	Tags        Optional[[]string]
+	IsVerified  Optional[bool]   `patch:"Verified"`
+	ClientNonce Optional[string] `patch:"-"`
+}
+
+func TestPatchStruct(t *testing.T) {
+	base := Profile{Name: "Ada", Age: 36, Tags: []string{"admin"}, Verified: true}
+
+	tests := []struct {
+		name        string
+		patch       ProfilePatch
+		expected    Profile
+		wantChanged []string
+	}{
+		{
+			name:        "Empty patch changes nothing",
+			patch:       ProfilePatch{},
+			expected:    base,
+			wantChanged: []string{},
+		},
+		{
+			name:        "Explicit zero values are applied",
+			patch:       ProfilePatch{Age: Some(0), IsVerified: Some(false)},
+			expected:    Profile{Name: "Ada", Age: 0, Tags: []string{"admin"}, Verified: false},
+			wantChanged: []string{"Age", "Verified"},
+		},
+		{
+			name:        "Setting the same value is not reported as a change",
+			patch:       ProfilePatch{Name: Some("Ada"), Tags: Some([]string{"ops"})},
+			expected:    Profile{Name: "Ada", Age: 36, Tags: []string{"ops"}, Verified: true},
+			wantChanged: []string{"Tags"},
+		},
+		{
+			name:        "Skipped fields never reach the base",
+			patch:       ProfilePatch{ClientNonce: Some("abc")},
+			expected:    base,
+			wantChanged: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, changes, err := PatchStruct(base, tc.patch)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, got)
+			}
+			if names := ChangedFields(changes); !reflect.DeepEqual(names, tc.wantChanged) {
+				t.Errorf("Changed fields: expected %v, got %v", tc.wantChanged, names)
+			}
+		})
+	}
+
+	if base.Age != 36 || !base.Verified {
+		t.Fatalf("PatchStruct mutated the base value: %+v", base)
+	}
+}
+
+func TestPatchStructUserSettings(t *testing.T) {
+	base := UserSettings{ID: "user-123", Notifications: true, Retries: 5}
+	patch := UserSettingsPatch{Retries: OptionalInt{Value: 0, Valid: true}}
+
+	got, changes, err := PatchStruct(base, patch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := UserSettings{ID: "user-123", Notifications: true, Retries: 0}
+	if got != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, got)
+	}
+	if len(changes) != 1 || changes[0].Old != 5 || changes[0].New != 0 {
+		t.Fatalf("Expected a single Retries 5 -> 0 change, got %v", changes)
+	}
+}
+
+func TestPatchStructMismatch(t *testing.T) {
+	base := Profile{Name: "Ada"}
+
+	tests := []struct {
+		name  string
+		patch any
+	}{
+		{"Unknown field", struct{ Email Optional[string] }{}},
+		{"Wrong value type", struct{ Age Optional[string] }{}},
+		{"Field is not an Optional", struct{ Name string }{}},
+		{"Patch is not a struct", 42},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := PatchStruct(base, tc.patch)
+			if !errors.Is(err, ErrPatchMismatch) {
+				t.Fatalf("Expected ErrPatchMismatch, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := Profile{Name: "Ada", Age: 36, Tags: []string{"a"}}
+	after := Profile{Name: "Ada", Age: 37, Tags: []string{"a", "b"}}
+
+	changes := Diff(before, after)
+	expected := []FieldChange{
+		{Field: "Age", Old: 36, New: 37},
+		{Field: "Tags", Old: []string{"a"}, New: []string{"a", "b"}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected %v, got %v", expected, changes)
+	}
+
+	if changes := Diff(before, before); len(changes) != 0 {
+		t.Fatalf("Expected no changes between identical values, got %v", changes)
+	}
+}
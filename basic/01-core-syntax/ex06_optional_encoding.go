@@ -0,0 +1,185 @@
+package core
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Context: Decoding PATCH Payloads with Explicit Null
+// The API gateway receives partial updates as JSON bodies and as form-encoded queries.
+// Clients use three distinct states for each field:
+//   - absent:            {}                  -> "do not change"
+//   - explicit null:     {"retries": null}   -> "reset to the default"
+//   - set:               {"retries": 0}      -> "update to this value"
+//
+// Why this matters: `encoding/json` only calls a field's `UnmarshalJSON` when the key
+// is present, so an `Optional[T]` that implements `json.Unmarshaler` can tell all three
+// states apart. A plain `int` (or even a `*int`) collapses "absent" and "null" into the
+// same thing and the difference is thrown away before your handler ever sees it.
+//
+// Conventions:
+// 1. JSON: absent leaves the Optional zero, `null` sets Valid+Null, anything else sets
+//    Valid+Value. Tag fields with `omitzero` so absent fields stay absent on the way out.
+// 2. Forms: a missing key is absent, a key with an empty value (`?retries=`) is an
+//    explicit null, anything else is parsed into T.
+
+var ErrInvalidFormValue = errors.New("invalid form value")
+
+var jsonNull = []byte("null")
+
+// UnmarshalJSON implements json.Unmarshaler. It is only invoked for keys that are present.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), jsonNull) {
+		*o = Null[T]()
+		return nil
+	}
+
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*o = Some(v)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. Both explicit nulls and unset values encode
+// as null; use the `omitzero` tag option to drop unset fields from the output entirely.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Valid || o.Null {
+		return jsonNull, nil
+	}
+	return json.Marshal(o.Value)
+}
+
+// formField is implemented by *Optional[T] so DecodeForm can fill fields without knowing T.
+type formField interface {
+	decodeFormValue(raw string) error
+}
+
+func (o *Optional[T]) decodeFormValue(raw string) error {
+	if raw == "" {
+		*o = Null[T]()
+		return nil
+	}
+
+	var v T
+	if err := parseFormValue(raw, &v); err != nil {
+		return err
+	}
+	*o = Some(v)
+	return nil
+}
+
+// parseFormValue converts a single form value into the value pointed to by dst.
+func parseFormValue(raw string, dst any) error {
+	if tu, ok := dst.(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(raw))
+	}
+
+	rv := reflect.ValueOf(dst).Elem()
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", rv.Type())
+	}
+	return nil
+}
+
+var formFieldType = reflect.TypeFor[formField]()
+
+// DecodeForm fills the Optional fields of the struct pointed to by dst from values.
+// The key for each field comes from its `form` tag, then its `json` tag, then its name.
+// Fields tagged `form:"-"` are skipped.
+func DecodeForm(values url.Values, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("DecodeForm: dst must be a non-nil pointer to a struct, got %T", dst)
+	}
+	rv = rv.Elem()
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		key := formKey(sf)
+		if key == "" {
+			continue
+		}
+
+		raw, present := values[key]
+		if !present {
+			continue
+		}
+
+		field := rv.Field(i).Addr()
+		if !field.Type().Implements(formFieldType) {
+			return fmt.Errorf("DecodeForm: field %q is %s, not an Optional", sf.Name, sf.Type)
+		}
+
+		var first string
+		if len(raw) > 0 {
+			first = raw[0]
+		}
+		if err := field.Interface().(formField).decodeFormValue(first); err != nil {
+			return fmt.Errorf("%w: %s=%q: %v", ErrInvalidFormValue, key, first, err)
+		}
+	}
+	return nil
+}
+
+// formKey returns the form key for a field, or "" if the field should be skipped.
+func formKey(sf reflect.StructField) string {
+	if tag, ok := sf.Tag.Lookup("form"); ok {
+		if tag == "-" {
+			return ""
+		}
+		if tag != "" {
+			return tag
+		}
+	}
+	if tag, ok := sf.Tag.Lookup("json"); ok {
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return sf.Name
+}
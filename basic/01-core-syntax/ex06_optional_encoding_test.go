@@ -0,0 +1,141 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestOptionalUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected UserSettingsPatch
+	}{
+		{
+			name:     "Absent fields stay unset",
+			payload:  `{}`,
+			expected: UserSettingsPatch{},
+		},
+		{
+			name:     "Explicit null is distinguishable from absent",
+			payload:  `{"retries": null}`,
+			expected: UserSettingsPatch{Retries: Null[int]()},
+		},
+		{
+			name:    "Explicit zero values are set",
+			payload: `{"notifications": false, "retries": 0}`,
+			expected: UserSettingsPatch{
+				Notifications: OptionalBool{Value: false, Valid: true},
+				Retries:       OptionalInt{Value: 0, Valid: true},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var patch UserSettingsPatch
+			if err := json.Unmarshal([]byte(tc.payload), &patch); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if patch != tc.expected {
+				t.Fatalf("Expected %+v, got %+v", tc.expected, patch)
+			}
+		})
+	}
+
+	var patch UserSettingsPatch
+	if err := json.Unmarshal([]byte(`{"retries": "three"}`), &patch); err == nil {
+		t.Fatalf("Expected a type error for a non-numeric retries value")
+	}
+}
+
+func TestOptionalMarshalJSON(t *testing.T) {
+	patch := UserSettingsPatch{Retries: Null[int]()}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != `{"retries":null}` {
+		t.Fatalf("Expected absent field omitted and null preserved, got %s", data)
+	}
+
+	patch = UserSettingsPatch{Notifications: Some(false), Retries: Some(3)}
+	data, err = json.Marshal(patch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != `{"notifications":false,"retries":3}` {
+		t.Fatalf("Unexpected encoding: %s", data)
+	}
+}
+
+func TestNullResetsThroughPatchStruct(t *testing.T) {
+	base := UserSettings{ID: "user-123", Notifications: true, Retries: 5}
+
+	var patch UserSettingsPatch
+	if err := json.Unmarshal([]byte(`{"retries": null}`), &patch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got, _, err := PatchStruct(base, patch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := UserSettings{ID: "user-123", Notifications: true, Retries: 0}
+	if got != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestDecodeForm(t *testing.T) {
+	type SearchPatch struct {
+		Query Optional[string]  `form:"q"`
+		Limit Optional[uint16]  `json:"limit"`
+		Ratio Optional[float64] // keyed by field name
+		Debug Optional[bool]    `form:"-"`
+	}
+
+	values := url.Values{
+		"q":     {"gophers"},
+		"limit": {""},
+		"Ratio": {"0.5"},
+		"Debug": {"true"},
+	}
+
+	var patch SearchPatch
+	if err := DecodeForm(values, &patch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if patch.Query != Some("gophers") {
+		t.Errorf("Query: expected set to gophers, got %+v", patch.Query)
+	}
+	if patch.Limit != Null[uint16]() {
+		t.Errorf("Limit: expected explicit null, got %+v", patch.Limit)
+	}
+	if patch.Ratio != Some(0.5) {
+		t.Errorf("Ratio: expected 0.5, got %+v", patch.Ratio)
+	}
+	if patch.Debug.Valid {
+		t.Errorf("Debug: expected skipped field to stay unset, got %+v", patch.Debug)
+	}
+}
+
+func TestDecodeFormErrors(t *testing.T) {
+	var patch UserSettingsPatch
+	err := DecodeForm(url.Values{"retries": {"many"}}, &patch)
+	if !errors.Is(err, ErrInvalidFormValue) {
+		t.Fatalf("Expected ErrInvalidFormValue, got %v", err)
+	}
+
+	if err := DecodeForm(url.Values{}, patch); err == nil {
+		t.Fatalf("Expected an error when dst is not a pointer")
+	}
+
+	var notOptional struct{ Retries int }
+	if err := DecodeForm(url.Values{"Retries": {"1"}}, &notOptional); err == nil {
+		t.Fatalf("Expected an error for a non-Optional field")
+	}
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Context: Strongly Typed Identifiers
+// Defined types stop `ProcessOrder(productID, userID)` from compiling, but they don't
+// stop a product ID string from being converted into a `UserID` at an API boundary.
+// Production IDs therefore also carry their kind in their string form (`usr_…`, `prd_…`),
+// and every way into the type (parsing, JSON, text, SQL) validates that prefix.
+//
+// Why this matters: The compiler protects in-process code paths, the prefix protects
+// everything the compiler can't see: logs, URLs, support tickets, and database rows.
+//
+// Format: `<prefix>_<26 chars>`, where the body is 128 bits rendered in lowercase
+// Crockford base32. The first 48 bits are a millisecond timestamp (so IDs sort roughly
+// by creation time) and the remaining 80 bits come from crypto/rand, which makes
+// collisions practically impossible even for IDs minted in the same millisecond.
+//
+// Conversions from raw strings are deliberately explicit: use `ParseUserID` at trust
+// boundaries and a plain `UserID(s)` conversion only for values you already trust.
+
+var ErrInvalidID = errors.New("invalid id")
+
+// TypedID is satisfied by every identifier type declared in this package.
+type TypedID interface {
+	~string
+	idPrefix() string
+}
+
+func (UserID) idPrefix() string    { return "usr" }
+func (ProductID) idPrefix() string { return "prd" }
+
+const idBodyLen = 26
+
+var idEncoding = base32.NewEncoding("0123456789abcdefghjkmnpqrstvwxyz").WithPadding(base32.NoPadding)
+
+// NewID mints a new, time-ordered, collision-resistant identifier of kind T.
+func NewID[T TypedID]() T {
+	var raw [16]byte
+	binary.BigEndian.PutUint64(raw[:8], uint64(time.Now().UnixMilli())<<16)
+	if _, err := rand.Read(raw[6:]); err != nil {
+		// crypto/rand never fails on supported platforms; an ID without entropy is worse than a crash.
+		panic(fmt.Sprintf("typed id: reading random bytes: %v", err))
+	}
+
+	var zero T
+	return T(zero.idPrefix() + "_" + idEncoding.EncodeToString(raw[:]))
+}
+
+// ParseID validates s as an identifier of kind T.
+func ParseID[T TypedID](s string) (T, error) {
+	var zero T
+	prefix := zero.idPrefix()
+
+	body, ok := strings.CutPrefix(s, prefix+"_")
+	if !ok {
+		return zero, fmt.Errorf("%w: %q must start with %q", ErrInvalidID, s, prefix+"_")
+	}
+	if len(body) != idBodyLen {
+		return zero, fmt.Errorf("%w: %q body must be %d characters", ErrInvalidID, s, idBodyLen)
+	}
+
+	raw, err := idEncoding.DecodeString(body)
+	// Re-encoding rejects bodies whose unused trailing bits are set, so each ID has
+	// exactly one valid spelling.
+	if err != nil || idEncoding.EncodeToString(raw) != body {
+		return zero, fmt.Errorf("%w: %q has a malformed body", ErrInvalidID, s)
+	}
+	return T(s), nil
+}
+
+func NewUserID() UserID       { return NewID[UserID]() }
+func NewProductID() ProductID { return NewID[ProductID]() }
+
+func ParseUserID(s string) (UserID, error)       { return ParseID[UserID](s) }
+func ParseProductID(s string) (ProductID, error) { return ParseID[ProductID](s) }
+
+// scanID implements the shared part of sql.Scanner. NULL scans into the zero ID.
+func scanID[T TypedID](dst *T, src any) error {
+	switch v := src.(type) {
+	case nil:
+		*dst = ""
+		return nil
+	case string:
+		id, err := ParseID[T](v)
+		if err != nil {
+			return err
+		}
+		*dst = id
+		return nil
+	case []byte:
+		return scanID(dst, string(v))
+	default:
+		return fmt.Errorf("%w: cannot scan %T into %T", ErrInvalidID, src, *dst)
+	}
+}
+
+// valueID implements the shared part of driver.Valuer. The zero ID is stored as NULL.
+func valueID[T TypedID](id T) (driver.Value, error) {
+	if id == "" {
+		return nil, nil
+	}
+	return string(id), nil
+}
+
+// unmarshalID implements the shared part of encoding.TextUnmarshaler (and therefore JSON).
+// Empty text decodes to the zero ID so that MarshalText round-trips.
+func unmarshalID[T TypedID](dst *T, text []byte) error {
+	if len(text) == 0 {
+		*dst = ""
+		return nil
+	}
+	id, err := ParseID[T](string(text))
+	if err != nil {
+		return err
+	}
+	*dst = id
+	return nil
+}
+
+func (u UserID) String() string                   { return string(u) }
+func (u UserID) IsZero() bool                     { return u == "" }
+func (u UserID) MarshalText() ([]byte, error)     { return []byte(u), nil }
+func (u *UserID) UnmarshalText(text []byte) error { return unmarshalID(u, text) }
+func (u *UserID) Scan(src any) error              { return scanID(u, src) }
+func (u UserID) Value() (driver.Value, error)     { return valueID(u) }
+
+func (p ProductID) String() string                   { return string(p) }
+func (p ProductID) IsZero() bool                     { return p == "" }
+func (p ProductID) MarshalText() ([]byte, error)     { return []byte(p), nil }
+func (p *ProductID) UnmarshalText(text []byte) error { return unmarshalID(p, text) }
+func (p *ProductID) Scan(src any) error              { return scanID(p, src) }
+func (p ProductID) Value() (driver.Value, error)     { return valueID(p) }
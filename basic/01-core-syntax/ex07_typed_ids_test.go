@@ -0,0 +1,124 @@
+package core
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Compile-time proof that the IDs plug into database/sql.
+var (
+	_ sql.Scanner   = (*UserID)(nil)
+	_ driver.Valuer = UserID("")
+	_ sql.Scanner   = (*ProductID)(nil)
+	_ driver.Valuer = ProductID("")
+)
+
+func TestNewIDFormat(t *testing.T) {
+	u := NewUserID()
+	if !strings.HasPrefix(u.String(), "usr_") || len(u) != len("usr_")+idBodyLen {
+		t.Fatalf("Unexpected user id format: %q", u)
+	}
+	if _, err := ParseUserID(u.String()); err != nil {
+		t.Fatalf("Generated id does not parse: %v", err)
+	}
+
+	p := NewProductID()
+	if !strings.HasPrefix(p.String(), "prd_") {
+		t.Fatalf("Unexpected product id format: %q", p)
+	}
+
+	seen := make(map[UserID]bool)
+	for i := 0; i < 10_000; i++ {
+		id := NewUserID()
+		if seen[id] {
+			t.Fatalf("Collision after %d ids: %s", i, id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestParseID(t *testing.T) {
+	valid := NewProductID().String()
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"Valid product id", valid, false},
+		{"User prefix is not a product", "usr_" + valid[len("prd_"):], true},
+		{"Legacy unprefixed id", "prod-abc", true},
+		{"Body too short", "prd_0123", true},
+		{"Character outside the alphabet", "prd_" + strings.Repeat("u", idBodyLen), true},
+		{"Uppercase is not canonical", strings.ToUpper(valid[:4]) + valid[4:], true},
+		{"Trailing bits set", "prd_" + strings.Repeat("0", idBodyLen-1) + "1", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseProductID(tc.input)
+			if tc.wantErr && !errors.Is(err, ErrInvalidID) {
+				t.Fatalf("Expected ErrInvalidID for %q, got %v", tc.input, err)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("Expected %q to parse, got %v", tc.input, err)
+			}
+		})
+	}
+}
+
+func TestTypedIDJSON(t *testing.T) {
+	type Order struct {
+		User    UserID    `json:"user"`
+		Product ProductID `json:"product"`
+	}
+
+	in := Order{User: NewUserID(), Product: NewProductID()}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var out Order
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out != in {
+		t.Fatalf("Round trip mismatch: %+v != %+v", out, in)
+	}
+
+	swapped := []byte(`{"user":"` + in.Product.String() + `","product":"` + in.User.String() + `"}`)
+	if err := json.Unmarshal(swapped, &out); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("Expected swapped ids to be rejected, got %v", err)
+	}
+}
+
+func TestTypedIDSQL(t *testing.T) {
+	want := NewUserID()
+
+	v, err := want.Value()
+	if err != nil || v != want.String() {
+		t.Fatalf("Value() = %v, %v", v, err)
+	}
+
+	var got UserID
+	if err := got.Scan([]byte(want)); err != nil || got != want {
+		t.Fatalf("Scan([]byte) = %q, %v", got, err)
+	}
+	if err := got.Scan(nil); err != nil || !got.IsZero() {
+		t.Fatalf("Scan(nil) should yield the zero id, got %q, %v", got, err)
+	}
+	if v, _ := got.Value(); v != nil {
+		t.Fatalf("Zero id should be stored as NULL, got %v", v)
+	}
+	if err := got.Scan(42); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("Expected ErrInvalidID scanning an int, got %v", err)
+	}
+	if err := got.Scan(NewProductID().String()); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("Expected ErrInvalidID scanning a product id, got %v", err)
+	}
+}
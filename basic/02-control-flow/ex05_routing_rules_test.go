@@ -0,0 +1,121 @@
+package control
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const tieredRules = `{
+  "default": "Priority-Low",
+  "rules": [
+    {"name": "vip-small", "priority": "Priority-High", "when": {"vip": true, "payload_lt": 100}},
+    {"name": "admin", "priority": "Priority-Critical", "when": {"path_prefix": "/admin"}},
+    {"name": "api-post", "priority": "Priority-Medium", "when": {"path_prefix": "/api", "method": "POST"}}
+  ]
+}`
+
+func TestRuleSetMatchesRoutePriorityTiers(t *testing.T) {
+	rs, err := ParseRuleSet(strings.NewReader(tieredRules))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		req      Request
+		expected string
+		rule     string
+	}{
+		{"VIP small payload", Request{Path: "/api/users", Method: "GET", IsVIP: true, PayloadSize: 50}, "Priority-High", "vip-small"},
+		{"VIP large payload falls through", Request{Path: "/admin/settings", Method: "POST", IsVIP: true, PayloadSize: 500}, "Priority-Critical", "admin"},
+		{"API POST is medium", Request{Path: "/api/users", Method: "post", PayloadSize: 200}, "Priority-Medium", "api-post"},
+		{"API GET is low", Request{Path: "/api/users", Method: "GET", PayloadSize: 200}, "Priority-Low", ""},
+		{"Random endpoint is low", Request{Path: "/health", Method: "GET", PayloadSize: 10}, "Priority-Low", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := rs.Explain(tc.req)
+			if d.Priority != tc.expected || d.Rule != tc.rule {
+				t.Fatalf("Explain() = %v, want %s via %q", d, tc.expected, tc.rule)
+			}
+			if d.IsDefault() != (tc.rule == "") {
+				t.Fatalf("IsDefault() = %v for %v", d.IsDefault(), d)
+			}
+		})
+	}
+}
+
+func TestParseRuleSetRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"Malformed JSON", `{"default": `},
+		{"Typo in condition key", `{"default": "Low", "rules": [{"name": "a", "priority": "High", "when": {"path_prefx": "/a"}}]}`},
+		{"Missing default", `{"rules": []}`},
+		{"Unnamed rule", `{"default": "Low", "rules": [{"priority": "High"}]}`},
+		{"Duplicate rule", `{"default": "Low", "rules": [{"name": "a", "priority": "High"}, {"name": "a", "priority": "Mid"}]}`},
+		{"Missing priority", `{"default": "Low", "rules": [{"name": "a"}]}`},
+		{"Second document", `{"default": "Low"}{"default": "High"}`},
+		{"Trailing garbage", `{"default": "Low"} garbage`},
+		{"Impossible payload range", `{"default": "Low", "rules": [{"name": "a", "priority": "High", "when": {"payload_gte": 10, "payload_lt": 10}}]}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseRuleSet(strings.NewReader(tc.config)); !errors.Is(err, ErrInvalidRules) {
+				t.Fatalf("Expected ErrInvalidRules, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRuleRouterReload(t *testing.T) {
+	rs, err := ParseRuleSet(strings.NewReader(tieredRules))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	router := NewRuleRouter(rs)
+	health := Request{Path: "/health", Method: "GET"}
+
+	if got := router.Route(health); got != "Priority-Low" {
+		t.Fatalf("Expected Priority-Low before reload, got %s", got)
+	}
+
+	if err := router.Reload(strings.NewReader(`{"default": "Priority-Low", "rules": [{"name": "x", "priority": "Priority-High", "when": {"pth": "/health"}}]}`)); err == nil {
+		t.Fatalf("Expected the typo'd reload to fail")
+	}
+	if got := router.Route(health); got != "Priority-Low" {
+		t.Fatalf("A failed reload must keep the previous rules, got %s", got)
+	}
+
+	if err := router.Reload(strings.NewReader(`{"default": "Priority-Low", "rules": [{"name": "health", "priority": "Priority-High", "when": {"path": "/health"}}]}`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d := router.Explain(health); d.Priority != "Priority-High" || d.Rule != "health" {
+		t.Fatalf("Expected the reloaded rule to apply, got %v", d)
+	}
+}
+
+func TestLoadRuleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(tieredRules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := LoadRuleFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rs.Rules) != 3 {
+		t.Fatalf("Expected 3 rules, got %d", len(rs.Rules))
+	}
+
+	if _, err := LoadRuleFile(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected os.ErrNotExist, got %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Context: A Streaming Command Interpreter
+// `EventLoopWorker` understands `SHUTDOWN` and `IGNORE*` over a slice. The job-control
+// sidecar reads an unbounded command stream instead, and needs more verbs:
+//
+//	PAUSE / RESUME   stop / restart executing commands (dropped while paused;
+//	                 BEGIN and END are still tracked)
+//	SKIP n           drop the next n executable commands
+//	BEGIN [label]    open a (possibly nested) batch scope
+//	END              close the innermost batch scope
+//	BREAK [label]    abandon the innermost batch, or the batch named label and every
+//	                 batch nested inside it, dropping commands until its END
+//	SHUTDOWN         stop reading the stream (honored even while paused or breaking)
+//
+// Why this matters: `BREAK label` is the stream equivalent of Go's labeled break.
+// The interpreter has to know *which* scope it is leaving, exactly like the compiler
+// does, otherwise a break inside a nested batch silently resumes the outer one.
+// Every line that was read ends up in the returned log with what happened to it,
+// so "did my command run?" is answered by data, not by grepping sidecar output.
+
+var (
+	ErrMalformedCommand  = errors.New("malformed command")
+	ErrUnknownScope      = errors.New("unknown batch scope")
+	ErrUnterminatedBatch = errors.New("unterminated batch")
+)
+
+// CommandHandler executes a single non-control command.
+type CommandHandler func(cmd string) error
+
+// CommandStatus records what the interpreter did with a line.
+type CommandStatus int
+
+const (
+	StatusRan      CommandStatus = iota // Handler ran and succeeded
+	StatusFailed                        // Handler ran and returned an error
+	StatusDropped                       // Never reached the handler (see Reason)
+	StatusControl                       // Control verb applied
+	StatusRejected                      // Control verb was malformed or invalid here
+)
+
+func (s CommandStatus) String() string {
+	switch s {
+	case StatusRan:
+		return "ran"
+	case StatusFailed:
+		return "failed"
+	case StatusDropped:
+		return "dropped"
+	case StatusControl:
+		return "control"
+	case StatusRejected:
+		return "rejected"
+	default:
+		return fmt.Sprintf("CommandStatus(%d)", int(s))
+	}
+}
+
+// LogEntry describes the fate of one input line.
+type LogEntry struct {
+	Line    int // 1-based position in the stream
+	Command string
+	Status  CommandStatus
+	Reason  string // Why a command was dropped
+	Err     error  // Handler error or rejection cause
+}
+
+// CommandLog is the structured record of an interpreter run.
+type CommandLog []LogEntry
+
+// Ran returns the commands that reached the handler and succeeded.
+func (l CommandLog) Ran() []string {
+	var out []string
+	for _, e := range l {
+		if e.Status == StatusRan {
+			out = append(out, e.Command)
+		}
+	}
+	return out
+}
+
+// Dropped returns the entries that never reached the handler.
+func (l CommandLog) Dropped() []LogEntry {
+	var out []LogEntry
+	for _, e := range l {
+		if e.Status == StatusDropped {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Err joins every per-command error, or returns nil if there were none.
+func (l CommandLog) Err() error {
+	var errs []error
+	for _, e := range l {
+		if e.Err != nil {
+			errs = append(errs, fmt.Errorf("line %d %q: %w", e.Line, e.Command, e.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Interpreter holds the state of one command stream. It is not safe for concurrent use.
+type Interpreter struct {
+	handler  CommandHandler
+	line     int
+	paused   bool
+	skip     int
+	scopes   []string // Labels of the open batches, innermost last
+	breakTo  int      // Index of the scope being abandoned, -1 when not breaking
+	shutdown bool
+	log      CommandLog
+}
+
+// NewInterpreter returns an interpreter that runs commands with handler.
+func NewInterpreter(handler CommandHandler) *Interpreter {
+	return &Interpreter{handler: handler, breakTo: -1}
+}
+
+// Log returns the entries recorded so far.
+func (in *Interpreter) Log() CommandLog {
+	return in.log
+}
+
+func (in *Interpreter) record(cmd string, status CommandStatus, reason string, err error) {
+	in.log = append(in.log, LogEntry{Line: in.line, Command: cmd, Status: status, Reason: reason, Err: err})
+}
+
+// Feed processes one line and reports whether the interpreter accepts more input.
+// Blank lines and lines starting with '#' are ignored without being logged.
+func (in *Interpreter) Feed(line string) bool {
+	if in.shutdown {
+		return false
+	}
+	in.line++
+
+	cmd := strings.TrimSpace(line)
+	if cmd == "" || strings.HasPrefix(cmd, "#") {
+		return true
+	}
+	verb, arg, _ := strings.Cut(cmd, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch {
+	case verb == "SHUTDOWN":
+		in.shutdown = true
+		in.record(cmd, StatusControl, "", nil)
+		return false
+	case in.breakTo >= 0:
+		in.feedWhileBreaking(cmd, verb)
+		return true
+	case in.paused && verb == "RESUME":
+		in.paused = false
+		in.record(cmd, StatusControl, "", nil)
+		return true
+	case in.paused && verb != "BEGIN" && verb != "END":
+		// BEGIN and END still fall through: the scope stack must match the stream
+		// when execution resumes.
+		in.record(cmd, StatusDropped, "paused", nil)
+		return true
+	}
+
+	switch verb {
+	case "PAUSE":
+		in.paused = true
+		in.record(cmd, StatusControl, "", nil)
+	case "RESUME":
+		in.record(cmd, StatusRejected, "", fmt.Errorf("%w: RESUME while not paused", ErrMalformedCommand))
+	case "SKIP":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			in.record(cmd, StatusRejected, "", fmt.Errorf("%w: SKIP needs a non-negative count", ErrMalformedCommand))
+			break
+		}
+		in.skip = n
+		in.record(cmd, StatusControl, "", nil)
+	case "BEGIN":
+		in.scopes = append(in.scopes, arg)
+		in.record(cmd, StatusControl, "", nil)
+	case "END":
+		if len(in.scopes) == 0 {
+			in.record(cmd, StatusRejected, "", fmt.Errorf("%w: END without BEGIN", ErrUnknownScope))
+			break
+		}
+		in.scopes = in.scopes[:len(in.scopes)-1]
+		in.record(cmd, StatusControl, "", nil)
+	case "BREAK":
+		target := in.findScope(arg)
+		if target < 0 {
+			in.record(cmd, StatusRejected, "", fmt.Errorf("%w: BREAK %q outside a matching batch", ErrUnknownScope, arg))
+			break
+		}
+		in.breakTo = target
+		in.record(cmd, StatusControl, "", nil)
+	default:
+		in.execute(cmd)
+	}
+	return true
+}
+
+// findScope returns the index of the innermost open scope named label ("" means the
+// innermost scope of any name), or -1.
+func (in *Interpreter) findScope(label string) int {
+	for i := len(in.scopes) - 1; i >= 0; i-- {
+		if label == "" || in.scopes[i] == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// feedWhileBreaking drops everything until the END that closes the abandoned scope,
+// tracking nested BEGIN/END pairs on the way.
+func (in *Interpreter) feedWhileBreaking(cmd, verb string) {
+	label := in.scopes[in.breakTo]
+	switch verb {
+	case "BEGIN":
+		in.scopes = append(in.scopes, "")
+	case "END":
+		in.scopes = in.scopes[:len(in.scopes)-1]
+		if len(in.scopes) == in.breakTo {
+			in.breakTo = -1
+			in.record(cmd, StatusControl, "", nil)
+			return
+		}
+	}
+	in.record(cmd, StatusDropped, "break "+strconv.Quote(label), nil)
+}
+
+func (in *Interpreter) execute(cmd string) {
+	switch {
+	case strings.HasPrefix(cmd, "IGNORE"):
+		in.record(cmd, StatusDropped, "ignored", nil)
+	case in.skip > 0:
+		in.skip--
+		in.record(cmd, StatusDropped, "skipped", nil)
+	default:
+		if err := in.handler(cmd); err != nil {
+			in.record(cmd, StatusFailed, "", err)
+			return
+		}
+		in.record(cmd, StatusRan, "", nil)
+	}
+}
+
+// Close finishes the stream. It reports batches that were still open when the input
+// ended without a SHUTDOWN.
+func (in *Interpreter) Close() error {
+	if in.shutdown || len(in.scopes) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d batch(es) still open at end of input", ErrUnterminatedBatch, len(in.scopes))
+}
+
+// RunCommands interprets commands from ch until SHUTDOWN, the channel closes, or ctx
+// is done.
+func RunCommands(ctx context.Context, ch <-chan string, handler CommandHandler) (CommandLog, error) {
+	in := NewInterpreter(handler)
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			return in.Log(), ctx.Err()
+		case cmd, ok := <-ch:
+			if !ok {
+				break loop
+			}
+			if !in.Feed(cmd) {
+				break loop
+			}
+		}
+	}
+	return in.Log(), in.Close()
+}
+
+// RunCommandReader interprets newline-separated commands from r until SHUTDOWN or EOF.
+func RunCommandReader(r io.Reader, handler CommandHandler) (CommandLog, error) {
+	in := NewInterpreter(handler)
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if !in.Feed(sc.Text()) {
+			break
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return in.Log(), err
+	}
+	return in.Log(), in.Close()
+}
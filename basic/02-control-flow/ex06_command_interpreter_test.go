@@ -0,0 +1,203 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func recordingHandler(ran *[]string) CommandHandler {
+	return func(cmd string) error {
+		if strings.HasPrefix(cmd, "FAIL") {
+			return errors.New("boom")
+		}
+		*ran = append(*ran, cmd)
+		return nil
+	}
+}
+
+func TestRunCommandReaderVerbs(t *testing.T) {
+	script := `
+START
+IGNORE_THIS
+# comments and blank lines are not logged
+
+PAUSE
+WHILE_PAUSED
+RESUME
+SKIP 2
+SKIPPED_A
+IGNORE_NOT_COUNTED
+SKIPPED_B
+AFTER_SKIP
+FAIL_ME
+SHUTDOWN
+SHOULD_NOT_PROCESS
+`
+	var ran []string
+	log, err := RunCommandReader(strings.NewReader(script), recordingHandler(&ran))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []string{"START", "AFTER_SKIP"}
+	if !reflect.DeepEqual(ran, want) || !reflect.DeepEqual(log.Ran(), want) {
+		t.Fatalf("Expected %v to run, handler saw %v, log says %v", want, ran, log.Ran())
+	}
+
+	reasons := map[string]string{}
+	for _, e := range log.Dropped() {
+		reasons[e.Command] = e.Reason
+	}
+	wantReasons := map[string]string{
+		"IGNORE_THIS":        "ignored",
+		"WHILE_PAUSED":       "paused",
+		"SKIPPED_A":          "skipped",
+		"IGNORE_NOT_COUNTED": "ignored",
+		"SKIPPED_B":          "skipped",
+	}
+	if !reflect.DeepEqual(reasons, wantReasons) {
+		t.Fatalf("Dropped reasons:\nExpected: %v\nGot:      %v", wantReasons, reasons)
+	}
+
+	last := log[len(log)-1]
+	if last.Command != "SHUTDOWN" || last.Status != StatusControl {
+		t.Fatalf("Expected the log to end at SHUTDOWN, got %+v", last)
+	}
+
+	for _, e := range log {
+		if e.Command == "FAIL_ME" {
+			if e.Status != StatusFailed || e.Err == nil || e.Line != 14 {
+				t.Fatalf("Expected FAIL_ME on line 14 to be recorded as failed, got %+v", e)
+			}
+		}
+	}
+	if err := log.Err(); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Expected log.Err() to surface the handler error, got %v", err)
+	}
+}
+
+func TestInterpreterNestedBreak(t *testing.T) {
+	script := `
+BEGIN outer
+A
+BEGIN inner
+B
+BREAK
+C
+END
+D
+BEGIN inner
+E
+BREAK outer
+F
+BEGIN deeper
+G
+END
+END
+H
+END
+I
+`
+	var ran []string
+	log, err := RunCommandReader(strings.NewReader(script), recordingHandler(&ran))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []string{"A", "B", "D", "E", "I"}
+	if !reflect.DeepEqual(ran, want) {
+		t.Fatalf("\nExpected: %v\nGot:      %v\nCheck which scope BREAK leaves.", want, ran)
+	}
+
+	var dropped []string
+	for _, e := range log.Dropped() {
+		dropped = append(dropped, e.Command)
+	}
+	wantDropped := []string{"C", "F", "BEGIN deeper", "G", "END", "END", "H"}
+	if !reflect.DeepEqual(dropped, wantDropped) {
+		t.Fatalf("\nExpected dropped: %v\nGot:              %v", wantDropped, dropped)
+	}
+}
+
+func TestInterpreterRejectsInvalidControl(t *testing.T) {
+	script := "RESUME\nSKIP many\nEND\nBEGIN a\nBREAK b\nOK\n"
+
+	var ran []string
+	log, err := RunCommandReader(strings.NewReader(script), recordingHandler(&ran))
+	if !errors.Is(err, ErrUnterminatedBatch) {
+		t.Fatalf("Expected ErrUnterminatedBatch for the open batch, got %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"OK"}) {
+		t.Fatalf("Expected rejected control verbs not to stop execution, ran %v", ran)
+	}
+
+	var rejected []string
+	for _, e := range log {
+		if e.Status == StatusRejected {
+			rejected = append(rejected, e.Command)
+		}
+	}
+	if !reflect.DeepEqual(rejected, []string{"RESUME", "SKIP many", "END", "BREAK b"}) {
+		t.Fatalf("Unexpected rejected commands: %v", rejected)
+	}
+	if err := log.Err(); !errors.Is(err, ErrMalformedCommand) || !errors.Is(err, ErrUnknownScope) {
+		t.Fatalf("Expected log.Err() to carry both rejection kinds, got %v", err)
+	}
+}
+
+func TestInterpreterTracksScopesWhilePaused(t *testing.T) {
+	script := "PAUSE\nBEGIN x\nEND x\nRESUME\nEND\nBEGIN a\nPAUSE\nBEGIN b\nRESUME\nA\nBREAK a\nB\nEND\nEND\nC\n"
+
+	var ran []string
+	log, err := RunCommandReader(strings.NewReader(script), recordingHandler(&ran))
+	if err != nil {
+		t.Fatalf("Expected every scope to be closed, got %v", err)
+	}
+	if err := log.Err(); !errors.Is(err, ErrUnknownScope) {
+		t.Fatalf("Expected the stray END to be rejected, got %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"A", "C"}) {
+		t.Fatalf("Expected BREAK a to skip the scope opened while paused, ran %v", ran)
+	}
+
+	var statuses []string
+	for _, e := range log[:5] {
+		statuses = append(statuses, e.Command+"="+e.Status.String())
+	}
+	want := []string{"PAUSE=control", "BEGIN x=control", "END x=control", "RESUME=control", "END=rejected"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("\nExpected: %v\nGot:      %v", want, statuses)
+	}
+}
+
+func TestRunCommandsChannel(t *testing.T) {
+	ch := make(chan string)
+	go func() {
+		for _, cmd := range []string{"A", "SHUTDOWN", "B"} {
+			select {
+			case ch <- cmd:
+			case <-time.After(100 * time.Millisecond):
+				return // The interpreter stopped reading after SHUTDOWN.
+			}
+		}
+	}()
+
+	var ran []string
+	log, err := RunCommands(context.Background(), ch, recordingHandler(&ran))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(log.Ran(), []string{"A"}) {
+		t.Fatalf("Expected only A to run, got %v", log.Ran())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RunCommands(ctx, make(chan string), recordingHandler(&ran)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package control
+
+import (
+	"cmp"
+	"iter"
+	"maps"
+	"slices"
+
+	"go-playbook/basic/08-strings-bytes-and-runes/text"
+)
+
+// Context: Deterministic Iteration with Range-over-Func
+// Reports and audit exports must be byte-stable across runs, but `range` over a map is
+// deliberately randomized, and `len(s)` or even `utf8.RuneCountInString(s)` miscounts
+// user-facing text: "👋🏽" is two runes and "é" may be written as "e" + U+0301.
+//
+// Why this matters: Since Go 1.23, `iter.Seq`/`iter.Seq2` let us package the "sort
+// the keys first" and "walk user-perceived characters" loops once, and callers keep
+// writing a plain `for k, v := range ...`:
+//
+//	for k, v := range SortedEntries(m) { ... } // byte-stable map walk
+//	for k, v := range ordered.All() { ... }    // insertion order
+//	for g := range Graphemes(s) { ... }        // "é👋🏽" yields "é", "👋🏽"
+//
+// Grapheme segmentation follows the extended grapheme cluster rules of Unicode
+// UAX #29 (CR LF, controls, combining and spacing marks, Hangul syllables, emoji
+// modifiers, ZWJ emoji sequences and regional-indicator flags). Extended_Pictographic
+// isn't exposed by the `unicode` package, so it is approximated by the emoji blocks.
+
+// SortedKeys yields the keys of m in ascending order.
+func SortedKeys[K cmp.Ordered, V any](m map[K]V) iter.Seq[K] {
+	return SortedKeysFunc(m, cmp.Compare[K])
+}
+
+// SortedKeysFunc yields the keys of m ordered by compare. Keys that compare equal are
+// yielded in an unspecified order, so compare should be a total order for stable output.
+func SortedKeysFunc[K comparable, V any](m map[K]V, compare func(a, b K) int) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		keys := slices.SortedFunc(maps.Keys(m), compare)
+		for _, k := range keys {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// SortedEntries yields the entries of m in ascending key order.
+func SortedEntries[K cmp.Ordered, V any](m map[K]V) iter.Seq2[K, V] {
+	return SortedEntriesFunc(m, cmp.Compare[K])
+}
+
+// SortedEntriesFunc yields the entries of m with keys ordered by compare.
+func SortedEntriesFunc[K comparable, V any](m map[K]V, compare func(a, b K) int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k := range SortedKeysFunc(m, compare) {
+			if !yield(k, m[k]) {
+				return
+			}
+		}
+	}
+}
+
+type orderedEntry[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next *orderedEntry[K, V]
+}
+
+// OrderedMap is a map that iterates in insertion order. Updating an existing key keeps
+// its position; deleting and re-inserting moves it to the end. The zero value is not
+// usable; create one with NewOrderedMap. It is not safe for concurrent use.
+type OrderedMap[K comparable, V any] struct {
+	index      map[K]*orderedEntry[K, V]
+	head, tail *orderedEntry[K, V]
+}
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{index: make(map[K]*orderedEntry[K, V])}
+}
+
+// Len returns the number of entries.
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.index)
+}
+
+// Get returns the value stored under key.
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if e, ok := m.index[key]; ok {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set stores value under key, appending the key if it is new.
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	if e, ok := m.index[key]; ok {
+		e.value = value
+		return
+	}
+
+	e := &orderedEntry[K, V]{key: key, value: value, prev: m.tail}
+	if m.tail != nil {
+		m.tail.next = e
+	} else {
+		m.head = e
+	}
+	m.tail = e
+	m.index[key] = e
+}
+
+// Delete removes key and reports whether it was present.
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	e, ok := m.index[key]
+	if !ok {
+		return false
+	}
+	delete(m.index, key)
+
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		m.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		m.tail = e.prev
+	}
+	// Leave e.next intact so an iterator currently positioned on e can still advance.
+	e.prev = nil
+	return true
+}
+
+// All yields the entries in insertion order. Deleting the current entry while
+// iterating is safe.
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for e := m.head; e != nil; e = e.next {
+			if !yield(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys yields the keys in insertion order.
+func (m *OrderedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range m.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values yields the values in insertion order.
+func (m *OrderedMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range m.All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Graphemes yields the user-perceived characters (extended grapheme clusters) of s.
+// Invalid UTF-8 bytes are yielded as single-byte clusters. The segmenter is shared
+// with the strings chapter: see text.Clusters.
+func Graphemes(s string) iter.Seq[string] {
+	return text.Clusters(s)
+}
+
+// GraphemeCount returns the number of user-perceived characters in s.
+func GraphemeCount(s string) int {
+	return text.GraphemeCount(s)
+}
@@ -0,0 +1,109 @@
+package control
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSortedKeys(t *testing.T) {
+	vips := map[string]bool{"Zack": true, "Abby": true, "Mick": true, "Carl": true}
+
+	for i := 0; i < 20; i++ {
+		got := slices.Collect(SortedKeys(vips))
+		if !reflect.DeepEqual(got, []string{"Abby", "Carl", "Mick", "Zack"}) {
+			t.Fatalf("Iteration %d: unexpected order %v", i, got)
+		}
+	}
+
+	byLengthThenDesc := func(a, b string) int {
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(b, a)
+	}
+	m := map[string]int{"bb": 1, "a": 2, "ccc": 3, "aa": 4}
+	got := slices.Collect(SortedKeysFunc(m, byLengthThenDesc))
+	if !reflect.DeepEqual(got, []string{"a", "bb", "aa", "ccc"}) {
+		t.Fatalf("Custom comparator order wrong: %v", got)
+	}
+
+	var pairs []string
+	for k, v := range SortedEntries(map[int]string{3: "c", 1: "a", 2: "b"}) {
+		pairs = append(pairs, strings.Repeat(v, k))
+		if k == 2 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(pairs, []string{"a", "bb"}) {
+		t.Fatalf("SortedEntries must honor early break, got %v", pairs)
+	}
+}
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMap[string, int]()
+	m.Set("c", 1)
+	m.Set("a", 2)
+	m.Set("b", 3)
+	m.Set("c", 10) // Update keeps position.
+
+	if got := slices.Collect(m.Keys()); !reflect.DeepEqual(got, []string{"c", "a", "b"}) {
+		t.Fatalf("Expected insertion order, got %v", got)
+	}
+	if v, ok := m.Get("c"); !ok || v != 10 {
+		t.Fatalf("Get(c) = %v, %v", v, ok)
+	}
+
+	m.Delete("c")
+	m.Set("c", 4) // Re-insert moves to the end.
+	if got := slices.Collect(m.Values()); !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Fatalf("Expected values [2 3 4], got %v", got)
+	}
+
+	// Deleting the current entry mid-iteration must not derail the walk.
+	var seen []string
+	for k := range m.All() {
+		seen = append(seen, k)
+		m.Delete(k)
+	}
+	if !reflect.DeepEqual(seen, []string{"a", "b", "c"}) || m.Len() != 0 {
+		t.Fatalf("Delete during iteration: saw %v, %d left", seen, m.Len())
+	}
+	if m.Delete("missing") {
+		t.Fatalf("Delete of a missing key should report false")
+	}
+}
+
+func TestGraphemes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"ASCII", "Hi!", []string{"H", "i", "!"}},
+		{"Japanese", "こんにちは", []string{"こ", "ん", "に", "ち", "は"}},
+		{"Combining acute accent", "e\u0301te\u0301", []string{"e\u0301", "t", "e\u0301"}},
+		{"Skin tone modifier", "\U0001F44B\U0001F3FD!", []string{"\U0001F44B\U0001F3FD", "!"}},
+		{"ZWJ family", "\U0001F468\u200d\U0001F469\u200d\U0001F467x", []string{"\U0001F468\u200d\U0001F469\u200d\U0001F467", "x"}},
+		{"Emoji presentation selector", "\u2764\ufe0f", []string{"\u2764\ufe0f"}},
+		{"Flags pair up", "\U0001F1EF\U0001F1F5\U0001F1FA\U0001F1F8\U0001F1EB", []string{"\U0001F1EF\U0001F1F5", "\U0001F1FA\U0001F1F8", "\U0001F1EB"}},
+		{"CRLF is one cluster", "a\r\nb", []string{"a", "\r\n", "b"}},
+		{"Hangul jamo and precomposed syllables", "\u1100\u1161\u11a8\uac00", []string{"\u1100\u1161\u11a8", "\uac00"}},
+		{"Devanagari spacing mark", "\u0915\u093f", []string{"\u0915\u093f"}},
+		{"Invalid UTF-8 bytes stand alone", "a\xffb\u0301", []string{"a", "\xff", "b\u0301"}},
+		{"Empty", "", nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := slices.Collect(Graphemes(tc.input))
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("Graphemes(%q) = %q, want %q", tc.input, got, tc.expected)
+			}
+			if n := GraphemeCount(tc.input); n != len(tc.expected) {
+				t.Fatalf("GraphemeCount(%q) = %d, want %d", tc.input, n, len(tc.expected))
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package functions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Context: Composable Transactions with Savepoints
+// `ExecuteTx` decorates one callback, but unit-of-work code composes helpers that each
+// want to "open a transaction" around their own work. Naively, the inner helper either
+// starts a second, independent transaction or commits the outer one halfway through.
+//
+// Why this matters: The same named-return + defer technique scales up. `TxRunner.ExecuteTx`
+// keeps the current transaction in the context. The outermost call begins and commits a
+// real transaction; every nested call becomes a SAVEPOINT that is released on success and
+// rolled back on failure, so a helper can fail without poisoning the whole unit of work.
+//
+// Rules:
+// 1. Hooks registered with `OnCommit` only run once the outermost transaction commits,
+//    and never for a savepoint that was rolled back.
+// 2. Hooks registered with `OnRollback` run (LIFO) when their savepoint or the
+//    transaction containing it is rolled back.
+// 3. A panic rolls back the current scope, runs its rollback hooks and is then
+//    re-raised with the original value: the runner cleans up, it does not swallow.
+//    A callback that never returns (runtime.Goexit) is rolled back too.
+// 4. Every rollback is recorded in the error chain as a `*RollbackError`, and
+//    `errors.Is(err, ErrRolledBack)` holds for any of them.
+
+var (
+	ErrRolledBack = errors.New("transaction rolled back")
+	ErrNoTx       = errors.New("no transaction in context")
+)
+
+// Tx is the unit of work the runner drives. *sql.Tx satisfies it.
+type Tx interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Commit() error
+	Rollback() error
+}
+
+// TxBeginner starts transactions.
+type TxBeginner interface {
+	BeginTx(ctx context.Context) (Tx, error)
+}
+
+// SQLBeginner adapts *sql.DB to TxBeginner.
+type SQLBeginner struct {
+	DB   *sql.DB
+	Opts *sql.TxOptions
+}
+
+func (b SQLBeginner) BeginTx(ctx context.Context) (Tx, error) {
+	return b.DB.BeginTx(ctx, b.Opts)
+}
+
+// RollbackError records that a transaction or savepoint was rolled back and why.
+type RollbackError struct {
+	Scope       string // "tx" or the savepoint name
+	Cause       error  // Error (or recovered panic) that triggered the rollback
+	RollbackErr error  // Error returned by the rollback itself, if any
+}
+
+func (e *RollbackError) Error() string {
+	msg := fmt.Sprintf("%s rolled back: %v", e.Scope, e.Cause)
+	if e.RollbackErr != nil {
+		msg += fmt.Sprintf(" (rollback failed: %v)", e.RollbackErr)
+	}
+	return msg
+}
+
+func (e *RollbackError) Unwrap() []error {
+	errs := []error{ErrRolledBack, e.Cause}
+	if e.RollbackErr != nil {
+		errs = append(errs, e.RollbackErr)
+	}
+	return errs
+}
+
+// txScope is one level of nesting: the root transaction or a savepoint inside it.
+type txScope struct {
+	runner     *TxRunner
+	tx         Tx
+	parent     *txScope
+	name       string
+	nextID     *int // Shared savepoint counter for the whole transaction
+	onCommit   []func()
+	onRollback []func()
+}
+
+type txKey struct{}
+
+// TxRunner runs callbacks inside transactions obtained from a TxBeginner.
+type TxRunner struct {
+	db TxBeginner
+}
+
+func NewTxRunner(db TxBeginner) *TxRunner {
+	return &TxRunner{db: db}
+}
+
+// TxFrom returns the transaction carried by ctx, so helpers can issue statements on it.
+func TxFrom(ctx context.Context) (Tx, bool) {
+	s, ok := ctx.Value(txKey{}).(*txScope)
+	if !ok {
+		return nil, false
+	}
+	return s.tx, true
+}
+
+// OnCommit registers fn to run after the outermost transaction commits.
+func OnCommit(ctx context.Context, fn func()) error {
+	s, ok := ctx.Value(txKey{}).(*txScope)
+	if !ok {
+		return ErrNoTx
+	}
+	s.onCommit = append(s.onCommit, fn)
+	return nil
+}
+
+// OnRollback registers fn to run if the current scope is rolled back.
+func OnRollback(ctx context.Context, fn func()) error {
+	s, ok := ctx.Value(txKey{}).(*txScope)
+	if !ok {
+		return ErrNoTx
+	}
+	s.onRollback = append(s.onRollback, fn)
+	return nil
+}
+
+// ExecuteTx runs fn inside a transaction. If ctx already carries a transaction from this
+// runner, fn runs inside a new savepoint of it instead.
+func (r *TxRunner) ExecuteTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	parent, nested := ctx.Value(txKey{}).(*txScope)
+	if nested && parent.runner != r {
+		nested = false // A different database: start an independent transaction.
+	}
+
+	var s *txScope
+	if nested {
+		*parent.nextID++
+		s = &txScope{runner: r, tx: parent.tx, parent: parent, name: fmt.Sprintf("sp_%d", *parent.nextID), nextID: parent.nextID}
+		if _, err := s.tx.Exec("SAVEPOINT " + s.name); err != nil {
+			return fmt.Errorf("savepoint %s: %w", s.name, err)
+		}
+	} else {
+		tx, err := r.db.BeginTx(ctx)
+		if err != nil {
+			return fmt.Errorf("begin tx: %w", err)
+		}
+		s = &txScope{runner: r, tx: tx, name: "tx", nextID: new(int)}
+	}
+
+	// completed stays false if fn panics or calls runtime.Goexit (t.FailNow does): in
+	// both cases recover alone cannot tell that fn never finished.
+	completed := false
+	defer func() {
+		if p := recover(); p != nil {
+			s.rollback(fmt.Errorf("panic: %v", p))
+			panic(p)
+		}
+		if !completed {
+			err = s.rollback(errors.New("callback exited without returning"))
+			return
+		}
+		if err != nil {
+			err = s.rollback(err)
+			return
+		}
+		err = s.finish()
+	}()
+
+	err = fn(context.WithValue(ctx, txKey{}, s))
+	completed = true
+	return err
+}
+
+// rollback undoes the scope, runs its rollback hooks and returns the recorded error.
+func (s *txScope) rollback(cause error) error {
+	var rbErr error
+	if s.parent != nil {
+		// ROLLBACK TO keeps the savepoint on the stack; release it so a failed
+		// helper leaves nothing behind in the parent.
+		_, rbErr = s.tx.Exec("ROLLBACK TO SAVEPOINT " + s.name)
+		if rbErr == nil {
+			_, rbErr = s.tx.Exec("RELEASE SAVEPOINT " + s.name)
+		}
+	} else {
+		rbErr = s.tx.Rollback()
+	}
+
+	for i := len(s.onRollback) - 1; i >= 0; i-- {
+		s.onRollback[i]()
+	}
+	return &RollbackError{Scope: s.name, Cause: cause, RollbackErr: rbErr}
+}
+
+// finish releases a savepoint into its parent, or commits the root transaction.
+func (s *txScope) finish() error {
+	if s.parent != nil {
+		if _, err := s.tx.Exec("RELEASE SAVEPOINT " + s.name); err != nil {
+			return s.rollback(fmt.Errorf("release: %w", err))
+		}
+		// The savepoint's work now belongs to the parent, and so do its hooks.
+		s.parent.onCommit = append(s.parent.onCommit, s.onCommit...)
+		s.parent.onRollback = append(s.parent.onRollback, s.onRollback...)
+		return nil
+	}
+
+	if err := s.tx.Commit(); err != nil {
+		// A failed commit leaves nothing applied; treat it like any other rollback.
+		for i := len(s.onRollback) - 1; i >= 0; i-- {
+			s.onRollback[i]()
+		}
+		return &RollbackError{Scope: s.name, Cause: fmt.Errorf("commit: %w", err)}
+	}
+	for _, fn := range s.onCommit {
+		fn()
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package functions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// fakeTx records every statement so tests can assert the exact SQL issued.
+type fakeTx struct {
+	log       *[]string
+	commitErr error
+}
+
+func (f *fakeTx) Exec(query string, args ...any) (sql.Result, error) {
+	*f.log = append(*f.log, query)
+	return nil, nil
+}
+
+func (f *fakeTx) Commit() error {
+	*f.log = append(*f.log, "COMMIT")
+	return f.commitErr
+}
+
+func (f *fakeTx) Rollback() error {
+	*f.log = append(*f.log, "ROLLBACK")
+	return nil
+}
+
+type fakeDB struct {
+	log       []string
+	commitErr error
+}
+
+func (d *fakeDB) BeginTx(ctx context.Context) (Tx, error) {
+	d.log = append(d.log, "BEGIN")
+	return &fakeTx{log: &d.log, commitErr: d.commitErr}, nil
+}
+
+func TestTxRunnerNestedSavepoints(t *testing.T) {
+	db := &fakeDB{}
+	runner := NewTxRunner(db)
+	var events []string
+	errCharge := errors.New("card declined")
+
+	err := runner.ExecuteTx(context.Background(), func(ctx context.Context) error {
+		tx, _ := TxFrom(ctx)
+		tx.Exec("INSERT order")
+		OnCommit(ctx, func() { events = append(events, "email receipt") })
+
+		// A helper that succeeds: its savepoint is released and its hooks survive.
+		runner.ExecuteTx(ctx, func(ctx context.Context) error {
+			tx.Exec("UPDATE stock")
+			OnCommit(ctx, func() { events = append(events, "publish stock event") })
+			return nil
+		})
+
+		// A helper that fails: only its own work is undone.
+		chargeErr := runner.ExecuteTx(ctx, func(ctx context.Context) error {
+			tx.Exec("INSERT charge")
+			OnCommit(ctx, func() { events = append(events, "must not run") })
+			OnRollback(ctx, func() { events = append(events, "release card hold") })
+			return errCharge
+		})
+		if !errors.Is(chargeErr, errCharge) || !errors.Is(chargeErr, ErrRolledBack) {
+			t.Errorf("Expected the savepoint error to wrap the cause and ErrRolledBack, got %v", chargeErr)
+		}
+		var rb *RollbackError
+		if !errors.As(chargeErr, &rb) || rb.Scope != "sp_2" {
+			t.Errorf("Expected a *RollbackError for sp_2, got %v", chargeErr)
+		}
+		return nil // The unit of work decides to commit without the charge.
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantSQL := []string{
+		"BEGIN", "INSERT order",
+		"SAVEPOINT sp_1", "UPDATE stock", "RELEASE SAVEPOINT sp_1",
+		"SAVEPOINT sp_2", "INSERT charge", "ROLLBACK TO SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_2",
+		"COMMIT",
+	}
+	if !reflect.DeepEqual(db.log, wantSQL) {
+		t.Fatalf("\nExpected SQL: %v\nGot:          %v", wantSQL, db.log)
+	}
+
+	wantEvents := []string{"release card hold", "email receipt", "publish stock event"}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Fatalf("\nExpected hooks: %v\nGot:            %v", wantEvents, events)
+	}
+}
+
+func TestTxRunnerOuterRollbackChain(t *testing.T) {
+	db := &fakeDB{}
+	runner := NewTxRunner(db)
+	cause := errors.New("constraint violation")
+	var events []string
+
+	err := runner.ExecuteTx(context.Background(), func(ctx context.Context) error {
+		OnRollback(ctx, func() { events = append(events, "outer") })
+		return runner.ExecuteTx(ctx, func(ctx context.Context) error {
+			OnRollback(ctx, func() { events = append(events, "inner") })
+			return cause
+		})
+	})
+
+	if !errors.Is(err, cause) {
+		t.Fatalf("Expected the root cause in the chain, got %v", err)
+	}
+	expected := "tx rolled back: sp_1 rolled back: constraint violation"
+	if err.Error() != expected {
+		t.Fatalf("Expected %q, got %q", expected, err.Error())
+	}
+	if !reflect.DeepEqual(events, []string{"inner", "outer"}) {
+		t.Fatalf("Expected rollback hooks inner then outer, got %v", events)
+	}
+	if db.log[len(db.log)-1] != "ROLLBACK" {
+		t.Fatalf("Expected the transaction to end with ROLLBACK, got %v", db.log)
+	}
+}
+
+func TestTxRunnerPanicIsReraisedAfterRollback(t *testing.T) {
+	db := &fakeDB{}
+	runner := NewTxRunner(db)
+	var events []string
+
+	defer func() {
+		r := recover()
+		if r != "segfault" {
+			t.Fatalf("Expected the original panic value to be re-raised, got %v", r)
+		}
+		want := []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1", "ROLLBACK"}
+		if !reflect.DeepEqual(db.log, want) {
+			t.Fatalf("\nExpected SQL: %v\nGot:          %v", want, db.log)
+		}
+		if !reflect.DeepEqual(events, []string{"inner", "outer"}) {
+			t.Fatalf("Expected both rollback hooks to run before the panic escaped, got %v", events)
+		}
+	}()
+
+	runner.ExecuteTx(context.Background(), func(ctx context.Context) error {
+		OnRollback(ctx, func() { events = append(events, "outer") })
+		return runner.ExecuteTx(ctx, func(ctx context.Context) error {
+			OnRollback(ctx, func() { events = append(events, "inner") })
+			panic("segfault")
+		})
+	})
+}
+
+func TestTxRunnerGoexitRollsBack(t *testing.T) {
+	db := &fakeDB{}
+	runner := NewTxRunner(db)
+	committed := false
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runner.ExecuteTx(context.Background(), func(ctx context.Context) error {
+			OnCommit(ctx, func() { committed = true })
+			runtime.Goexit()
+			return nil
+		})
+	}()
+	<-done
+
+	if committed || !reflect.DeepEqual(db.log, []string{"BEGIN", "ROLLBACK"}) {
+		t.Fatalf("Expected a Goexit to roll back, committed=%v SQL=%v", committed, db.log)
+	}
+}
+
+func TestTxRunnerCommitFailure(t *testing.T) {
+	db := &fakeDB{commitErr: sql.ErrConnDone}
+	runner := NewTxRunner(db)
+	committed, rolledBack := false, false
+
+	err := runner.ExecuteTx(context.Background(), func(ctx context.Context) error {
+		OnCommit(ctx, func() { committed = true })
+		OnRollback(ctx, func() { rolledBack = true })
+		return nil
+	})
+
+	if !errors.Is(err, sql.ErrConnDone) || !errors.Is(err, ErrRolledBack) {
+		t.Fatalf("Expected a rolled-back commit failure, got %v", err)
+	}
+	if committed || !rolledBack {
+		t.Fatalf("Expected only rollback hooks to run, committed=%v rolledBack=%v", committed, rolledBack)
+	}
+}
+
+func TestOnCommitWithoutTx(t *testing.T) {
+	if err := OnCommit(context.Background(), func() {}); !errors.Is(err, ErrNoTx) {
+		t.Fatalf("Expected ErrNoTx, got %v", err)
+	}
+	if _, ok := TxFrom(context.Background()); ok {
+		t.Fatalf("Expected no transaction in a bare context")
+	}
+}
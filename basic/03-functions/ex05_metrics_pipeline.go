@@ -0,0 +1,242 @@
+package functions
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Context: A Composable Metrics Sanitization Pipeline
+// `SanitizeAndSum` clamps at a hardcoded 100 and mutates the caller's backing array.
+// The ingestion layer needs more than one rule (clamp, drop NaN/negative, winsorize,
+// smooth out spikes), for ints and floats alike, and needs to know what each rule did.
+//
+// Why this matters: Each stage is just a function value, so rules compose without a
+// framework. The pipeline owns the copying: it copies the variadic input once and
+// hands every stage its own copy, so neither a caller's slice nor an earlier stage's
+// output can be corrupted by a later stage. Because it keeps both sides of every stage,
+// the pipeline (not the stage) produces the change report, so a custom stage gets
+// accurate reporting for free.
+
+// Number is any integer or floating-point type.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Point is a metric value tagged with its position in the original input.
+type Point[T Number] struct {
+	Index int
+	Value T
+}
+
+// Stage is a named transformation over points. Fn may drop points or change their
+// values; it must not invent new indexes. Fn receives a slice it is free to modify.
+type Stage[T Number] struct {
+	Name string
+	Fn   func(points []Point[T]) []Point[T]
+}
+
+// PointChange records what a stage did to one point.
+type PointChange[T Number] struct {
+	Index   int
+	Before  T
+	After   T
+	Dropped bool
+}
+
+func (c PointChange[T]) String() string {
+	if c.Dropped {
+		return fmt.Sprintf("#%d %v dropped", c.Index, c.Before)
+	}
+	return fmt.Sprintf("#%d %v -> %v", c.Index, c.Before, c.After)
+}
+
+// StageReport lists the points a single stage changed, in input order.
+type StageReport[T Number] struct {
+	Stage   string
+	Changes []PointChange[T]
+}
+
+// SanitizeResult is the output of a pipeline run.
+type SanitizeResult[T Number] struct {
+	Points []Point[T] // Surviving points, in input order
+	Sum    T
+	Report []StageReport[T]
+}
+
+// Count returns the number of surviving points.
+func (r SanitizeResult[T]) Count() int {
+	return len(r.Points)
+}
+
+// Mean returns the average of the surviving points, or 0 if none survived.
+func (r SanitizeResult[T]) Mean() float64 {
+	if len(r.Points) == 0 {
+		return 0
+	}
+	return float64(r.Sum) / float64(len(r.Points))
+}
+
+// Values returns the surviving values.
+func (r SanitizeResult[T]) Values() []T {
+	out := make([]T, len(r.Points))
+	for i, p := range r.Points {
+		out[i] = p.Value
+	}
+	return out
+}
+
+// Pipeline runs stages in order.
+type Pipeline[T Number] struct {
+	stages []Stage[T]
+}
+
+func NewPipeline[T Number](stages ...Stage[T]) *Pipeline[T] {
+	// Copy so the caller can't reorder our stages through their own slice.
+	return &Pipeline[T]{stages: slices.Clone(stages)}
+}
+
+// Run sanitizes metrics and aggregates the result. metrics is never modified.
+func (p *Pipeline[T]) Run(metrics ...T) SanitizeResult[T] {
+	points := make([]Point[T], len(metrics))
+	for i, m := range metrics {
+		points[i] = Point[T]{Index: i, Value: m}
+	}
+
+	res := SanitizeResult[T]{Report: make([]StageReport[T], 0, len(p.stages))}
+	for _, st := range p.stages {
+		next := st.Fn(slices.Clone(points))
+		res.Report = append(res.Report, StageReport[T]{Stage: st.Name, Changes: diffPoints(points, next)})
+		points = next
+	}
+
+	res.Points = points
+	for _, pt := range points {
+		res.Sum += pt.Value
+	}
+	return res
+}
+
+// diffPoints reports every point of before that was dropped or changed in after.
+func diffPoints[T Number](before, after []Point[T]) []PointChange[T] {
+	kept := make(map[int]T, len(after))
+	for _, p := range after {
+		kept[p.Index] = p.Value
+	}
+
+	var changes []PointChange[T]
+	for _, p := range before {
+		v, ok := kept[p.Index]
+		switch {
+		case !ok:
+			changes = append(changes, PointChange[T]{Index: p.Index, Before: p.Value, Dropped: true})
+		case v != p.Value && !(isNaN(v) && isNaN(p.Value)):
+			changes = append(changes, PointChange[T]{Index: p.Index, Before: p.Value, After: v})
+		}
+	}
+	return changes
+}
+
+func isNaN[T Number](v T) bool {
+	return v != v
+}
+
+// mapValues builds a stage that rewrites each value independently.
+func mapValues[T Number](name string, fn func(T) T) Stage[T] {
+	return Stage[T]{Name: name, Fn: func(points []Point[T]) []Point[T] {
+		for i := range points {
+			points[i].Value = fn(points[i].Value)
+		}
+		return points
+	}}
+}
+
+// dropWhere builds a stage that removes points matching pred.
+func dropWhere[T Number](name string, pred func(T) bool) Stage[T] {
+	return Stage[T]{Name: name, Fn: func(points []Point[T]) []Point[T] {
+		return slices.DeleteFunc(points, func(p Point[T]) bool { return pred(p.Value) })
+	}}
+}
+
+// Clamp limits every value to [lo, hi].
+func Clamp[T Number](lo, hi T) Stage[T] {
+	if lo > hi {
+		panic(fmt.Sprintf("Clamp: lo %v > hi %v", lo, hi))
+	}
+	return mapValues(fmt.Sprintf("clamp[%v,%v]", lo, hi), func(v T) T {
+		return min(max(v, lo), hi)
+	})
+}
+
+// DropNaN removes NaN and ±Inf values. It is a no-op for integer types.
+func DropNaN[T Number]() Stage[T] {
+	return dropWhere("drop-nan", func(v T) bool {
+		return isNaN(v) || math.IsInf(float64(v), 0)
+	})
+}
+
+// DropNegative removes values below zero.
+func DropNegative[T Number]() Stage[T] {
+	return dropWhere("drop-negative", func(v T) bool { return v < 0 })
+}
+
+// Winsorize clamps values below the lower percentile and above the upper percentile
+// (nearest-rank, 0-100) to those percentiles, limiting the pull of extreme values
+// without dropping them. NaN points are left out of the percentiles and passed through
+// unchanged; drop them with an earlier stage if they should not reach the aggregate.
+func Winsorize[T Number](lowerPct, upperPct float64) Stage[T] {
+	if lowerPct < 0 || upperPct > 100 || lowerPct > upperPct {
+		panic(fmt.Sprintf("Winsorize: invalid percentiles %v..%v", lowerPct, upperPct))
+	}
+	return Stage[T]{Name: fmt.Sprintf("winsorize[p%v,p%v]", lowerPct, upperPct), Fn: func(points []Point[T]) []Point[T] {
+		sorted := make([]T, 0, len(points))
+		for _, p := range points {
+			if !isNaN(p.Value) { // NaN would sort first and become the lower bound
+				sorted = append(sorted, p.Value)
+			}
+		}
+		if len(sorted) == 0 {
+			return points
+		}
+		slices.Sort(sorted)
+
+		lo, hi := percentile(sorted, lowerPct), percentile(sorted, upperPct)
+		for i := range points {
+			points[i].Value = min(max(points[i].Value, lo), hi)
+		}
+		return points
+	}}
+}
+
+// percentile returns the nearest-rank percentile of an ascending slice.
+func percentile[T Number](sorted []T, pct float64) T {
+	rank := int(math.Ceil(pct / 100 * float64(len(sorted))))
+	return sorted[min(max(rank-1, 0), len(sorted)-1)]
+}
+
+// LimitRate caps how far each value may move away from the previous surviving value.
+// A spike is pulled back to prev±maxStep instead of skewing the aggregate; a genuine
+// level shift still gets through over several points.
+func LimitRate[T Number](maxStep T) Stage[T] {
+	if maxStep < 0 {
+		panic(fmt.Sprintf("LimitRate: negative maxStep %v", maxStep))
+	}
+	return Stage[T]{Name: fmt.Sprintf("limit-rate[%v]", maxStep), Fn: func(points []Point[T]) []Point[T] {
+		for i := 1; i < len(points); i++ {
+			prev, v := points[i-1].Value, points[i].Value
+			// For integers near the bounds of T, prev±maxStep wraps around. A bound that
+			// wrapped lies beyond the range of T, so nothing can cross it: saturate by
+			// skipping that side instead of comparing against the wrapped value.
+			up, down := prev+maxStep, prev-maxStep
+			switch {
+			case up >= prev && v > up:
+				points[i].Value = up
+			case down <= prev && v < down:
+				points[i].Value = down
+			}
+		}
+		return points
+	}}
+}
@@ -0,0 +1,117 @@
+package functions
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPipelineDoesNotMutateCaller(t *testing.T) {
+	callerSlice := []int{50, 150, 20}
+	original := append([]int(nil), callerSlice...)
+
+	res := NewPipeline(Clamp(0, 100)).Run(callerSlice...)
+
+	if res.Sum != 170 {
+		t.Errorf("Expected sum 170, got %d", res.Sum)
+	}
+	if !reflect.DeepEqual(callerSlice, original) {
+		t.Fatalf("CRITICAL: pipeline mutated the caller's backing array: %v -> %v", original, callerSlice)
+	}
+
+	want := []PointChange[int]{{Index: 1, Before: 150, After: 100}}
+	if !reflect.DeepEqual(res.Report[0].Changes, want) {
+		t.Fatalf("Expected report %v, got %v", want, res.Report[0].Changes)
+	}
+}
+
+func TestPipelineFloatStages(t *testing.T) {
+	p := NewPipeline(
+		DropNaN[float64](),
+		DropNegative[float64](),
+		Clamp(0.0, 1000.0),
+	)
+	res := p.Run(12.5, math.NaN(), -3, 2000, math.Inf(1), 7.5)
+
+	if got := res.Values(); !reflect.DeepEqual(got, []float64{12.5, 1000, 7.5}) {
+		t.Fatalf("Unexpected survivors: %v", got)
+	}
+	if res.Sum != 1020 || res.Count() != 3 || res.Mean() != 340 {
+		t.Fatalf("Unexpected aggregate: sum=%v count=%d mean=%v", res.Sum, res.Count(), res.Mean())
+	}
+
+	dropped := map[string][]int{}
+	for _, r := range res.Report {
+		for _, c := range r.Changes {
+			dropped[r.Stage] = append(dropped[r.Stage], c.Index)
+		}
+	}
+	want := map[string][]int{
+		"drop-nan":      {1, 4},
+		"drop-negative": {2},
+		"clamp[0,1000]": {3},
+	}
+	if !reflect.DeepEqual(dropped, want) {
+		t.Fatalf("\nExpected per-stage indexes: %v\nGot:                       %v", want, dropped)
+	}
+}
+
+func TestWinsorize(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 1000}
+	res := NewPipeline(Winsorize[int](10, 90)).Run(values...)
+
+	want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 9}
+	if got := res.Values(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	if changes := res.Report[0].Changes; len(changes) != 1 || changes[0].Index != 9 || changes[0].Before != 1000 {
+		t.Fatalf("Expected only the 1000 outlier to be winsorized, got %v", changes)
+	}
+}
+
+func TestWinsorizeIgnoresNaN(t *testing.T) {
+	res := NewPipeline(Winsorize[float64](0, 75)).Run(1, math.NaN(), 2, 3, 100)
+
+	got := res.Values()
+	if !math.IsNaN(got[1]) || got[0] != 1 || got[2] != 2 || got[3] != 3 || got[4] != 3 {
+		t.Fatalf("Expected [1 NaN 2 3 3], got %v", got)
+	}
+}
+
+func TestLimitRateNearBounds(t *testing.T) {
+	res := NewPipeline(LimitRate[int64](10)).Run(math.MinInt64, math.MaxInt64, math.MaxInt64-5, math.MinInt64)
+
+	want := []int64{math.MinInt64, math.MinInt64 + 10, math.MinInt64 + 20, math.MinInt64 + 10}
+	if got := res.Values(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestLimitRate(t *testing.T) {
+	res := NewPipeline(LimitRate[uint32](10)).Run(100, 105, 500, 120, 0)
+
+	want := []uint32{100, 105, 115, 120, 110}
+	if got := res.Values(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestPipelineIsolatesStages(t *testing.T) {
+	// A misbehaving custom stage that scribbles over its input must not corrupt the
+	// report for the stage before it.
+	scribble := Stage[int]{Name: "scribble", Fn: func(points []Point[int]) []Point[int] {
+		for i := range points {
+			points[i].Value = -1
+		}
+		return points[:1]
+	}}
+
+	res := NewPipeline(Clamp(0, 10), scribble).Run(5, 50)
+
+	if got := res.Report[0].Changes; len(got) != 1 || got[0].After != 10 {
+		t.Fatalf("Clamp report corrupted by a later stage: %v", got)
+	}
+	if got := res.Report[1].Changes; len(got) != 2 || !got[1].Dropped {
+		t.Fatalf("Expected scribble to change #0 and drop #1, got %v", got)
+	}
+}
@@ -0,0 +1,189 @@
+// Package detach copies small pieces of large buffers into memory they own, so that
+// keeping a 10-byte ID alive does not keep its megabyte payload alive with it.
+//
+// `Extract` copies a set of ranges into one compact arena: one allocation sized to
+// exactly what the caller keeps, shared by all the returned slices. `View` is the
+// explicit zero-copy escape hatch for short-lived access.
+//
+// With `SetDebug(true)`, every slice handed out is attributed to the line that asked
+// for it. `Report` then shows, per call site, how many bytes are retained and how many
+// bytes of backing array they are pinning. Entries are released by the garbage
+// collector, so a site whose pinned bytes keep growing is holding on to payloads.
+package detach
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrOutOfRange = errors.New("detach: range out of bounds")
+
+// Range is the half-open byte range [Start, End) of a payload.
+type Range struct {
+	Start, End int
+}
+
+// Len returns the number of bytes in r.
+func (r Range) Len() int {
+	return r.End - r.Start
+}
+
+func (r Range) check(payload []byte) error {
+	if r.Start < 0 || r.End < r.Start || r.End > len(payload) {
+		return fmt.Errorf("%w: [%d:%d] of %d bytes", ErrOutOfRange, r.Start, r.End, len(payload))
+	}
+	return nil
+}
+
+// Extract copies each range of payload into a single arena and returns one slice per
+// range, in order. The result shares no memory with payload. Each slice's capacity is
+// clipped to its length, so appending to one never overwrites its neighbour.
+func Extract(payload []byte, ranges ...Range) ([][]byte, error) {
+	total := 0
+	for _, r := range ranges {
+		if err := r.check(payload); err != nil {
+			return nil, err
+		}
+		total += r.Len()
+	}
+
+	arena := make([]byte, total)
+	out := make([][]byte, len(ranges))
+	off := 0
+	for i, r := range ranges {
+		n := copy(arena[off:], payload[r.Start:r.End])
+		out[i] = arena[off : off+n : off+n]
+		off += n
+	}
+
+	if debug.Load() && total > 0 {
+		track(&arena[0], total, total)
+	}
+	return out, nil
+}
+
+// Clone copies a single range of payload into a new, exactly sized slice.
+func Clone(payload []byte, r Range) ([]byte, error) {
+	if err := r.check(payload); err != nil {
+		return nil, err
+	}
+	out := make([]byte, r.Len())
+	copy(out, payload[r.Start:r.End])
+
+	if debug.Load() && len(out) > 0 {
+		track(&out[0], len(out), len(out))
+	}
+	return out, nil
+}
+
+// View returns payload[r.Start:r.End] without copying. The result keeps all of
+// payload's backing array reachable; only use it for data that is dropped as soon as
+// the payload is.
+func View(payload []byte, r Range) ([]byte, error) {
+	if err := r.check(payload); err != nil {
+		return nil, err
+	}
+
+	if debug.Load() && cap(payload) > 0 {
+		// cap is a lower bound: the array may also extend before payload[0].
+		track(&payload[:1][0], r.Len(), cap(payload))
+	}
+	return payload[r.Start:r.End:r.End], nil
+}
+
+// SiteStats summarizes the slices handed out to one call site.
+type SiteStats struct {
+	Site     string // "file.go:line function"
+	Slices   int64  // Slices handed out since the last ResetDebug
+	Live     int64  // Of those, slices whose backing array has not been collected yet
+	Retained int64  // Bytes the live slices actually expose
+	Pinned   int64  // Bytes of backing array the live slices keep reachable
+}
+
+// Waste returns the bytes that are kept alive but not exposed to the caller.
+func (s SiteStats) Waste() int64 {
+	return s.Pinned - s.Retained
+}
+
+var (
+	debug atomic.Bool
+
+	mu    sync.Mutex
+	sites = map[string]*SiteStats{}
+	epoch uint64 // Bumped by ResetDebug so stale cleanups don't touch new stats
+)
+
+// SetDebug turns per-call-site retention tracking on or off. Tracking costs a stack
+// lookup per call, so it is meant for debugging and tests, not steady-state production.
+func SetDebug(on bool) {
+	debug.Store(on)
+}
+
+// ResetDebug forgets every recorded call site.
+func ResetDebug() {
+	mu.Lock()
+	defer mu.Unlock()
+	sites = map[string]*SiteStats{}
+	epoch++
+}
+
+// Report returns a snapshot of the recorded call sites, worst offender (most pinned
+// bytes) first.
+func Report() []SiteStats {
+	mu.Lock()
+	out := make([]SiteStats, 0, len(sites))
+	for _, s := range sites {
+		out = append(out, *s)
+	}
+	mu.Unlock()
+
+	slices.SortFunc(out, func(a, b SiteStats) int {
+		return cmp.Or(cmp.Compare(b.Pinned, a.Pinned), cmp.Compare(a.Site, b.Site))
+	})
+	return out
+}
+
+type release struct {
+	site             string
+	epoch            uint64
+	retained, pinned int64
+}
+
+// track attributes a slice to the caller of the exported function and releases the
+// attribution once the backing array containing ptr is collected.
+func track(ptr *byte, retained, pinned int) {
+	site := "unknown"
+	pcs := make([]uintptr, 1)
+	if runtime.Callers(3, pcs) > 0 { // runtime.Callers, track, exported function
+		frame, _ := runtime.CallersFrames(pcs).Next()
+		site = fmt.Sprintf("%s:%d %s", frame.File, frame.Line, frame.Function)
+	}
+
+	mu.Lock()
+	s, ok := sites[site]
+	if !ok {
+		s = &SiteStats{Site: site}
+		sites[site] = s
+	}
+	s.Slices++
+	s.Live++
+	s.Retained += int64(retained)
+	s.Pinned += int64(pinned)
+	rel := release{site: site, epoch: epoch, retained: int64(retained), pinned: int64(pinned)}
+	mu.Unlock()
+
+	runtime.AddCleanup(ptr, func(rel release) {
+		mu.Lock()
+		defer mu.Unlock()
+		if s, ok := sites[rel.site]; ok && rel.epoch == epoch {
+			s.Live--
+			s.Retained -= rel.retained
+			s.Pinned -= rel.pinned
+		}
+	}, rel)
+}
@@ -0,0 +1,157 @@
+package detach
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// overlaps reports whether b points into the backing array of payload.
+func overlaps(payload, b []byte) bool {
+	if cap(b) == 0 || cap(payload) == 0 {
+		return false
+	}
+	lo := uintptr(unsafe.Pointer(unsafe.SliceData(payload)))
+	hi := lo + uintptr(cap(payload))
+	p := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+	return p >= lo && p < hi
+}
+
+func TestExtract(t *testing.T) {
+	payload := make([]byte, 1<<20)
+	copy(payload, "TX-99887766|user=alice|")
+
+	parts, err := Extract(payload, Range{0, 11}, Range{17, 22}, Range{5, 5})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	want := []string{"TX-99887766", "alice", ""}
+	for i, p := range parts {
+		if string(p) != want[i] {
+			t.Errorf("part %d: Expected %q, got %q", i, want[i], p)
+		}
+		if overlaps(payload, p) {
+			t.Errorf("part %d shares the payload's backing array", i)
+		}
+		if cap(p) != len(p) {
+			t.Errorf("part %d: Expected capacity clipped to %d, got %d", i, len(p), cap(p))
+		}
+	}
+
+	// All parts live in one arena: the second starts right after the first.
+	if unsafe.SliceData(parts[1]) != (*byte)(unsafe.Add(unsafe.Pointer(unsafe.SliceData(parts[0])), 11)) {
+		t.Errorf("Expected parts to be packed into one arena")
+	}
+
+	// Appending to one part must not clobber the next one.
+	_ = append(parts[0], "XXXXX"...)
+	if string(parts[1]) != "alice" {
+		t.Errorf("Appending to a part overwrote its neighbour: %q", parts[1])
+	}
+}
+
+func TestRangeChecks(t *testing.T) {
+	payload := []byte("0123456789")
+	tests := []Range{{-1, 2}, {4, 3}, {0, 11}}
+	for _, r := range tests {
+		if _, err := Extract(payload, r); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Extract %v: Expected ErrOutOfRange, got %v", r, err)
+		}
+		if _, err := Clone(payload, r); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Clone %v: Expected ErrOutOfRange, got %v", r, err)
+		}
+		if _, err := View(payload, r); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("View %v: Expected ErrOutOfRange, got %v", r, err)
+		}
+	}
+}
+
+func TestCloneAndView(t *testing.T) {
+	payload := []byte("header:body")
+
+	c, err := Clone(payload, Range{0, 6})
+	if err != nil || string(c) != "header" || overlaps(payload, c) {
+		t.Errorf("Clone: Expected an independent %q, got %q (err %v)", "header", c, err)
+	}
+
+	v, err := View(payload, Range{7, 11})
+	if err != nil || string(v) != "body" || !overlaps(payload, v) {
+		t.Errorf("View: Expected a zero-copy %q, got %q (err %v)", "body", v, err)
+	}
+}
+
+func siteFor(t *testing.T, marker string) SiteStats {
+	t.Helper()
+	for _, s := range Report() {
+		if strings.Contains(s.Site, marker) {
+			return s
+		}
+	}
+	t.Fatalf("no call site matching %q in %+v", marker, Report())
+	return SiteStats{}
+}
+
+func viewHeader(payload []byte) []byte {
+	v, _ := View(payload, Range{0, 10})
+	return v
+}
+
+func extractHeader(payload []byte) []byte {
+	parts, _ := Extract(payload, Range{0, 10})
+	return parts[0]
+}
+
+func TestDebugReportAttributesCallSites(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+	ResetDebug()
+
+	payload := make([]byte, 1<<20)
+	leaky := viewHeader(payload)
+	compact := extractHeader(payload)
+
+	v := siteFor(t, "viewHeader")
+	if v.Live != 1 || v.Retained != 10 || v.Pinned != 1<<20 || v.Waste() != 1<<20-10 {
+		t.Errorf("viewHeader: Expected 10 bytes pinning 1MiB, got %+v", v)
+	}
+	e := siteFor(t, "extractHeader")
+	if e.Live != 1 || e.Retained != 10 || e.Pinned != 10 {
+		t.Errorf("extractHeader: Expected 10 bytes pinning 10, got %+v", e)
+	}
+	if Report()[0].Site != v.Site {
+		t.Errorf("Expected the view site to be reported first, got %s", Report()[0].Site)
+	}
+	runtime.KeepAlive(leaky)
+	runtime.KeepAlive(compact)
+}
+
+func TestDebugReportReleasesCollectedArrays(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+	ResetDebug()
+
+	func() {
+		payload := make([]byte, 1<<20)
+		_ = viewHeader(payload)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		runtime.GC()
+		s := siteFor(t, "viewHeader")
+		if s.Live == 0 && s.Pinned == 0 {
+			if s.Slices != 1 {
+				t.Errorf("Expected the historical count to be kept, got %d", s.Slices)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the collected payload to be released, got %+v", s)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
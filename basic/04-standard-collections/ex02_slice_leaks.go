@@ -1,5 +1,7 @@
 package collections
 
+import "go-playbook/basic/04-standard-collections/detach"
+
 // Context: Memory leak via subslicing
 // You are processing massive 100MB text payloads representing HTTP bodies.
 // You only need to extract a tiny 10-byte transaction ID from the header of the payload
@@ -12,14 +14,14 @@ package collections
 // Requirements:
 // 1. `ExtractTxID` must return a byte slice containing the first 10 bytes of the payload.
 // 2. The returned slice MUST NOT share a backing array with the `largePayload`.
+//
+// The copy is done by the shared `detach` package, which also powers the GC chapter's
+// `ExtractMetadata` and can attribute pinned bytes to call sites in debug mode.
 
 func ExtractTxID(largePayload []byte) []byte {
-	if len(largePayload) < 10 {
-		return nil
+	txID, err := detach.Clone(largePayload, detach.Range{Start: 0, End: 10})
+	if err != nil {
+		return nil // Payload shorter than a transaction ID.
 	}
-
-	// BUG: This returns a slice header pointing to `largePayload`'s backing array.
-	// This leaks `largePayload` entirely.
-	// TODO: Fix this to return a freshly allocated 10-byte slice.
-	return largePayload[:10]
+	return txID
 }
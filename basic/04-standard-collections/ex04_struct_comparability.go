@@ -1,5 +1,13 @@
 package collections
 
+import (
+	"crypto/sha256"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
 // Context: Struct Comparability
 // You are building a deduplication filter. You want to store incoming HTTP request
 // signatures in a map to quickly check if you've seen them before in the last 5 seconds.
@@ -10,42 +18,64 @@ package collections
 // If you use an interface that happens to hold a slice/map at runtime, it PANICS.
 //
 // Requirements:
-// 1. `RequestSignature` currently contains a `[]string` for headers, making it incomparable.
+// 1. `RequestSignature` used to contain a `[]string` for headers, making it incomparable.
 // 2. Refactor `RequestSignature` so it IS comparable and can be used as a map key.
 //    (Hint: How can you safely represent a list of strings in a comparable way?
 //    Maybe a comma-separated string, or an array if the size is strictly fixed.
 //    For this exercise, converting the headers slice to a single joined string is best).
 // 3. Update `IsDuplicate` to compile and work.
 
-// BUG: This struct cannot be used as a map key because `Headers` is a slice.
-// TODO: Refactor this to be comparable.
+// RequestSignature is the canonical, comparable identity of a request. Two requests
+// that differ only in header order, header name case or surrounding whitespace get
+// the same signature. Build one with CreateSignature or SignatureOf.
 type RequestSignature struct {
-	Method  string
-	Path    string
-	Headers []string
+	Method   string
+	Path     string
+	Headers  string            // Canonical "name: value" lines, sorted and joined by "\n"
+	BodyHash [sha256.Size]byte // Zero unless the body is part of the identity
 }
 
-// TODO: Fix the signature of this map and the logic below
-// after you fix `RequestSignature`.
-var seenRequests = make(map[interface{}]bool) // using interface{} to mock the failure
+// CreateSignature canonicalizes a request description. Each header is a
+// "Name: value" line.
+func CreateSignature(method, urlPath string, headers []string) RequestSignature {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		name, value, _ := strings.Cut(h, ":")
+		lines = append(lines, strings.ToLower(strings.TrimSpace(name))+": "+strings.Join(strings.Fields(value), " "))
+	}
+	slices.Sort(lines)
 
-func IsDuplicate(req RequestSignature) bool {
-	// BUG: If seenRequests was map[RequestSignature]bool, this wouldn't compile
-	// because RequestSignature is not comparable.
-	// Since we mocked it with map[interface{}]bool, this will actually panic at runtime
-	// when passing an incomparable struct!
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+	return RequestSignature{
+		Method:  strings.ToUpper(method),
+		Path:    path.Clean(urlPath),
+		Headers: strings.Join(slices.Compact(lines), "\n"),
+	}
+}
 
-	// TODO: After fixing `RequestSignature` to be comparable, change `seenRequests` to
-	// `map[RequestSignature]bool` and fix this logic.
+// WithBody returns a copy of sig that also identifies the request by its body.
+func (sig RequestSignature) WithBody(body []byte) RequestSignature {
+	sig.BodyHash = sha256.Sum256(body)
+	return sig
+}
 
-	if seenRequests[req] {
-		return true
-	}
-	seenRequests[req] = true
-	return false
+// DedupWindow is how long IsDuplicate remembers a signature; at most MaxTrackedRequests
+// signatures are remembered at once.
+const (
+	DedupWindow        = 5 * time.Second
+	MaxTrackedRequests = 10_000
+)
+
+var seenRequests = NewWindowStore[RequestSignature, struct{}](DedupWindow, MaxTrackedRequests)
+
+// IsDuplicate reports whether req was already seen within DedupWindow.
+func IsDuplicate(req RequestSignature) bool {
+	_, claimed := seenRequests.Claim(req, struct{}{})
+	return !claimed
 }
 
 func ResetCache() {
-	// seenRequests = make(map[RequestSignature]bool)
-	seenRequests = make(map[interface{}]bool)
+	seenRequests = NewWindowStore[RequestSignature, struct{}](DedupWindow, MaxTrackedRequests)
 }
@@ -0,0 +1,176 @@
+package collections
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+	"time"
+)
+
+// Context: A Persistent, Append-Only Audit Log
+// Several goroutines hand audit trails to each other. With a bare `[]string`, two holders
+// of the same slice can both `append` into the same spare capacity and silently overwrite
+// each other's entries (the exact aliasing bug `AppendAuditLog` demonstrates).
+//
+// Why this matters: `AuditLog` is a small value type (a pointer and a slice header), so
+// taking a snapshot is a plain O(1) copy. Appends share the backing array for as long as
+// the history is linear: the first append from the newest snapshot claims the next slot
+// in place. Any other snapshot that appends afterwards has diverged, so it copies its
+// prefix into a fresh array first. No append is ever visible through another snapshot.
+//
+// Each entry also stores the SHA-256 of its predecessor, so editing or dropping any
+// entry in a serialized (JSON Lines) log breaks the chain and `Verify` reports where.
+
+var ErrTampered = errors.New("audit log hash chain broken")
+
+// AuditEntry is one immutable record in the log. Seq, PrevHash and Hash are assigned
+// by the log.
+type AuditEntry struct {
+	Seq      int       `json:"seq"`
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`
+	Action   string    `json:"action"`
+	Detail   string    `json:"detail,omitempty"`
+	PrevHash string    `json:"prev_hash"`
+	Hash     string    `json:"hash"`
+}
+
+// computeHash hashes every field except Hash itself.
+func (e AuditEntry) computeHash() string {
+	e.Hash = ""
+	// Marshaling a struct is deterministic: fields are always emitted in declaration order.
+	payload, _ := json.Marshal(e)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditStore tracks how far the shared backing array has been claimed.
+type auditStore struct {
+	mu  sync.Mutex
+	tip int // Number of slots claimed in the backing array shared by this store
+}
+
+// AuditLog is an immutable view of an audit trail. The zero value is an empty log and
+// values are safe to share and append to from multiple goroutines.
+type AuditLog struct {
+	store   *auditStore
+	entries []AuditEntry // Never written at indexes below len(entries)
+}
+
+// Len returns the number of entries visible through this snapshot.
+func (l AuditLog) Len() int {
+	return len(l.entries)
+}
+
+// At returns the i-th entry.
+func (l AuditLog) At(i int) AuditEntry {
+	return l.entries[i]
+}
+
+// Head returns the hash of the last entry, or "" for an empty log.
+func (l AuditLog) Head() string {
+	if len(l.entries) == 0 {
+		return ""
+	}
+	return l.entries[len(l.entries)-1].Hash
+}
+
+// All yields the entries in order.
+func (l AuditLog) All() iter.Seq2[int, AuditEntry] {
+	return func(yield func(int, AuditEntry) bool) {
+		for i, e := range l.entries {
+			if !yield(i, e) {
+				return
+			}
+		}
+	}
+}
+
+// Append returns a new log with e added. The receiver is unchanged. Seq, PrevHash and
+// Hash are overwritten; a zero Time is set to the current time.
+func (l AuditLog) Append(e AuditEntry) AuditLog {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	// Strip the monotonic reading and location so the hash survives a JSON round trip.
+	e.Time = e.Time.UTC().Round(0)
+	e.Seq = len(l.entries)
+	e.PrevHash = l.Head()
+	e.Hash = e.computeHash()
+
+	if l.store != nil {
+		l.store.mu.Lock()
+		defer l.store.mu.Unlock()
+		if l.store.tip == len(l.entries) {
+			// We are the newest snapshot of this history: claim the next slot in place.
+			l.store.tip++
+			return AuditLog{store: l.store, entries: append(l.entries, e)}
+		}
+	}
+
+	// Empty log or a diverged snapshot: start a new backing array.
+	entries := make([]AuditEntry, len(l.entries), 2*len(l.entries)+1)
+	copy(entries, l.entries)
+	return AuditLog{store: &auditStore{tip: len(entries) + 1}, entries: append(entries, e)}
+}
+
+// Verify recomputes the hash chain and reports the first inconsistent entry.
+func (l AuditLog) Verify() error {
+	prev := ""
+	for i, e := range l.entries {
+		switch {
+		case e.Seq != i:
+			return fmt.Errorf("%w: entry %d has seq %d", ErrTampered, i, e.Seq)
+		case e.PrevHash != prev:
+			return fmt.Errorf("%w: entry %d does not follow its predecessor", ErrTampered, i)
+		case e.computeHash() != e.Hash:
+			return fmt.Errorf("%w: entry %d content does not match its hash", ErrTampered, i)
+		}
+		prev = e.Hash
+	}
+	return nil
+}
+
+// WriteJSONL writes one JSON object per line.
+func (l AuditLog) WriteJSONL(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, e := range l.entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadAuditJSONL parses a JSON Lines audit log and verifies its hash chain.
+func ReadAuditJSONL(r io.Reader) (AuditLog, error) {
+	var entries []AuditEntry
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e AuditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return AuditLog{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return AuditLog{}, err
+	}
+
+	l := AuditLog{store: &auditStore{tip: len(entries)}, entries: entries}
+	if err := l.Verify(); err != nil {
+		return AuditLog{}, err
+	}
+	return l, nil
+}
@@ -0,0 +1,162 @@
+package collections
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func auditActions(l AuditLog) []string {
+	var out []string
+	for _, e := range l.All() {
+		out = append(out, e.Action)
+	}
+	return out
+}
+
+func TestAuditLogSnapshotsAreIsolated(t *testing.T) {
+	var base AuditLog
+	base = base.Append(AuditEntry{Actor: "alice", Action: "login"})
+	base = base.Append(AuditEntry{Actor: "alice", Action: "read"})
+
+	snapshot := base // O(1): just a copy of the value
+	a := base.Append(AuditEntry{Actor: "alice", Action: "write"})
+	b := snapshot.Append(AuditEntry{Actor: "bob", Action: "delete"})
+
+	tests := []struct {
+		name string
+		log  AuditLog
+		want string
+	}{
+		{"base", base, "login,read"},
+		{"snapshot", snapshot, "login,read"},
+		{"a", a, "login,read,write"},
+		{"b", b, "login,read,delete"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(auditActions(tt.log), ","); got != tt.want {
+			t.Errorf("%s: Expected %q, got %q", tt.name, tt.want, got)
+		}
+		if err := tt.log.Verify(); err != nil {
+			t.Errorf("%s: Expected a valid chain, got %v", tt.name, err)
+		}
+	}
+
+	// The linear history keeps appending in place; the diverged one starts a new store.
+	if a.store != base.store {
+		t.Errorf("Expected the first append from the newest snapshot to share storage")
+	}
+	if b.store == base.store {
+		t.Errorf("Expected a diverged append to copy its prefix")
+	}
+}
+
+func TestAuditLogHashChain(t *testing.T) {
+	var l AuditLog
+	for _, action := range []string{"create", "update", "delete"} {
+		l = l.Append(AuditEntry{Actor: "svc", Action: action})
+	}
+
+	for i, e := range l.All() {
+		if e.Seq != i {
+			t.Errorf("Expected seq %d, got %d", i, e.Seq)
+		}
+		if i > 0 && e.PrevHash != l.At(i-1).Hash {
+			t.Errorf("Entry %d does not link to its predecessor", i)
+		}
+	}
+	if l.Head() != l.At(2).Hash {
+		t.Errorf("Expected Head to be the hash of the last entry")
+	}
+}
+
+func TestAuditLogJSONLRoundTrip(t *testing.T) {
+	var l AuditLog
+	l = l.Append(AuditEntry{Actor: "alice", Action: "login", Time: time.Date(2024, 1, 2, 3, 4, 5, 6, time.FixedZone("X", 3600))})
+	l = l.Append(AuditEntry{Actor: "alice", Action: "export", Detail: "report.csv"})
+
+	var buf bytes.Buffer
+	if err := l.WriteJSONL(&buf); err != nil {
+		t.Fatalf("WriteJSONL: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Fatalf("Expected 2 lines, got %d:\n%s", lines, buf.String())
+	}
+
+	got, err := ReadAuditJSONL(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadAuditJSONL: %v", err)
+	}
+	if got.Len() != 2 || got.Head() != l.Head() {
+		t.Fatalf("Expected head %s, got %s (len %d)", l.Head(), got.Head(), got.Len())
+	}
+
+	// A decoded log keeps growing the same chain.
+	got = got.Append(AuditEntry{Actor: "alice", Action: "logout"})
+	if err := got.Verify(); err != nil {
+		t.Errorf("Expected a valid chain after appending to a decoded log, got %v", err)
+	}
+}
+
+func TestAuditLogDetectsTampering(t *testing.T) {
+	var l AuditLog
+	for _, action := range []string{"grant", "revoke", "grant"} {
+		l = l.Append(AuditEntry{Actor: "admin", Action: action})
+	}
+	var buf bytes.Buffer
+	if err := l.WriteJSONL(&buf); err != nil {
+		t.Fatalf("WriteJSONL: %v", err)
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"edited entry", strings.Replace(buf.String(), `"revoke"`, `"noop"`, 1)},
+		{"dropped entry", lines[0] + lines[2]},
+		{"reordered entries", lines[1] + lines[0] + lines[2]},
+	}
+	for _, tt := range tests {
+		_, err := ReadAuditJSONL(strings.NewReader(tt.input))
+		if !errors.Is(err, ErrTampered) {
+			t.Errorf("%s: Expected ErrTampered, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestAuditLogConcurrentAppends(t *testing.T) {
+	var base AuditLog
+	base = base.Append(AuditEntry{Actor: "root", Action: "start"})
+
+	const workers = 8
+	results := make([]AuditLog, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := base
+			for range 50 {
+				l = l.Append(AuditEntry{Actor: "worker", Action: "tick"})
+			}
+			results[i] = l
+		}()
+	}
+	wg.Wait()
+
+	for i, l := range results {
+		if l.Len() != 51 {
+			t.Errorf("worker %d: Expected 51 entries, got %d", i, l.Len())
+		}
+		if err := l.Verify(); err != nil {
+			t.Errorf("worker %d: %v", i, err)
+		}
+	}
+	if base.Len() != 1 {
+		t.Errorf("Expected the shared base to stay at 1 entry, got %d", base.Len())
+	}
+}
@@ -0,0 +1,309 @@
+package collections
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Context: Idempotent Request Handling
+// Webhook senders retry on timeouts, so the same charge request can arrive two or
+// three times. `IsDuplicate` answers "have I seen this?", but a real idempotency layer
+// also has to forget old requests (bounded memory) and answer a retry with the
+// *original* response instead of doing the work again.
+//
+// Why this matters: The building block is a map keyed by a comparable struct. A
+// `WindowStore` adds a first-seen time to every key, expires keys in arrival order and
+// drops the oldest key once it is full. `Claim` checks and records atomically, so two
+// concurrent retries can never both win. `ClaimPinned` holds a key for work still in
+// progress: it neither expires nor is evicted until `Unpin` starts its window.
+//
+// `IdempotencyMiddleware` keys the store by the `Idempotency-Key` header:
+// 1. The first request runs the handler; its response is recorded and sent.
+// 2. A retry with the same key gets the recorded response, marked with
+//    `Idempotent-Replayed: true`. A retry that arrives while the first request is
+//    still running waits for it.
+// 3. Reusing a key for a different method, path or body is a client bug: 422.
+// 4. Server errors (5xx) and panics are not recorded, so the client can retry them.
+//    Retries already waiting on such a request claim the key again and run afresh.
+// 5. A key stays claimed while its handler runs, however long that takes, so it can
+//    never run twice at once.
+
+// WindowStore remembers keys for a fixed window after they were first claimed, holding
+// at most maxEntries keys. Pinned keys are not counted against that bound's evictions:
+// while every key is pinned the store may briefly hold more. It is safe for concurrent
+// use.
+type WindowStore[K comparable, V any] struct {
+	mu      sync.Mutex
+	window  time.Duration
+	max     int
+	entries map[K]windowEntry[V]
+	queue   []windowKey[K] // Claims in arrival order; may contain stale (forgotten) keys
+	seq     uint64
+	now     func() time.Time
+}
+
+type windowEntry[V any] struct {
+	value  V
+	at     time.Time
+	seq    uint64
+	pinned bool // Claimed with ClaimPinned and not yet unpinned; not in the queue
+}
+
+type windowKey[K comparable] struct {
+	key K
+	seq uint64
+}
+
+// NewWindowStore returns a store that remembers keys for window. maxEntries <= 0 means
+// unbounded.
+func NewWindowStore[K comparable, V any](window time.Duration, maxEntries int) *WindowStore[K, V] {
+	return &WindowStore[K, V]{
+		window:  window,
+		max:     maxEntries,
+		entries: make(map[K]windowEntry[V]),
+		now:     time.Now,
+	}
+}
+
+// Claim records value under key unless key was claimed within the window. It returns
+// the value of the existing claim and false, or value and true if this call won.
+func (s *WindowStore[K, V]) Claim(key K, value V) (V, bool) {
+	return s.claim(key, value, false)
+}
+
+// ClaimPinned is Claim for work that is still in progress: the claim does not expire
+// and is not evicted until Unpin (or Forget) is called for key.
+func (s *WindowStore[K, V]) ClaimPinned(key K, value V) (V, bool) {
+	return s.claim(key, value, true)
+}
+
+func (s *WindowStore[K, V]) claim(key K, value V, pinned bool) (V, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+	if e, ok := s.entries[key]; ok {
+		return e.value, false
+	}
+
+	for s.max > 0 && len(s.entries) >= s.max && len(s.queue) > 0 {
+		s.popOldest()
+	}
+	s.seq++
+	s.entries[key] = windowEntry[V]{value: value, at: now, seq: s.seq, pinned: pinned}
+	if !pinned {
+		s.queue = append(s.queue, windowKey[K]{key: key, seq: s.seq})
+	}
+	return value, true
+}
+
+// Unpin releases a pinned claim; its window starts now. It does nothing if key is not
+// pinned.
+func (s *WindowStore[K, V]) Unpin(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || !e.pinned {
+		return
+	}
+	s.seq++
+	e.pinned, e.at, e.seq = false, s.now(), s.seq
+	s.entries[key] = e
+	s.queue = append(s.queue, windowKey[K]{key: key, seq: s.seq})
+}
+
+// Forget drops key so that the next Claim for it succeeds.
+func (s *WindowStore[K, V]) Forget(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key) // The queue entry becomes stale and is skipped later.
+}
+
+// Len returns the number of keys currently remembered.
+func (s *WindowStore[K, V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(s.now())
+	return len(s.entries)
+}
+
+// expire drops every claim older than the window. Claims are queued in time order, so
+// it stops at the first live one.
+func (s *WindowStore[K, V]) expire(now time.Time) {
+	for len(s.queue) > 0 {
+		head := s.queue[0]
+		if e, ok := s.entries[head.key]; ok && e.seq == head.seq && now.Sub(e.at) < s.window {
+			return
+		}
+		s.popOldest()
+	}
+}
+
+// popOldest removes the head of the queue and its claim, unless the claim was
+// forgotten and re-made since.
+func (s *WindowStore[K, V]) popOldest() {
+	for len(s.queue) > 0 {
+		head := s.queue[0]
+		var zero windowKey[K]
+		s.queue[0] = zero
+		s.queue = s.queue[1:]
+		if e, ok := s.entries[head.key]; ok && e.seq == head.seq {
+			delete(s.entries, head.key)
+			return
+		}
+	}
+}
+
+// recordedResponse is the outcome of the first request for an idempotency key.
+type recordedResponse struct {
+	signature RequestSignature
+	done      chan struct{} // Closed once the fields below are set
+	forgotten bool          // A 5xx or panic: not recorded, so waiters claim again
+	status    int
+	header    http.Header
+	body      []byte
+}
+
+// responseRecorder buffers a handler's response so it can be stored and replayed.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+// SignatureOf returns the canonical signature of r including the listed headers.
+// If body is non-nil it is part of the identity too.
+func SignatureOf(r *http.Request, body []byte, headers ...string) RequestSignature {
+	lines := make([]string, 0, len(headers))
+	for _, name := range headers {
+		for _, v := range r.Header.Values(name) {
+			lines = append(lines, name+": "+v)
+		}
+	}
+	sig := CreateSignature(r.Method, r.URL.Path, lines)
+	if body != nil {
+		sig = sig.WithBody(body)
+	}
+	return sig
+}
+
+// DefaultMaxIdempotentBody is the largest request body IdempotencyMiddleware buffers.
+const DefaultMaxIdempotentBody = 1 << 20
+
+// IdempotencyMiddleware replays the first response for every Idempotency-Key seen
+// within window, remembering at most maxEntries keys. Requests without the header
+// pass straight through.
+func IdempotencyMiddleware(window time.Duration, maxEntries int, next http.Handler) http.Handler {
+	return IdempotencyMiddlewareWithLimit(window, maxEntries, DefaultMaxIdempotentBody, next)
+}
+
+// IdempotencyMiddlewareWithLimit is IdempotencyMiddleware with a bound on the body it
+// buffers to sign a request. Larger bodies get 413 before the key is claimed.
+// maxBody <= 0 means DefaultMaxIdempotentBody.
+func IdempotencyMiddlewareWithLimit(window time.Duration, maxEntries int, maxBody int64, next http.Handler) http.Handler {
+	if maxBody <= 0 {
+		maxBody = DefaultMaxIdempotentBody
+	}
+	store := NewWindowStore[string, *recordedResponse](window, maxEntries)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "reading request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sig := SignatureOf(r, body)
+
+		for {
+			rec := &recordedResponse{signature: sig, done: make(chan struct{})}
+			first, claimed := store.ClaimPinned(key, rec)
+			if claimed {
+				serveFirst(w, r, store, key, rec, next)
+				return
+			}
+			if first.signature != sig {
+				http.Error(w, "Idempotency-Key reused for a different request", http.StatusUnprocessableEntity)
+				return
+			}
+			select {
+			case <-first.done:
+			case <-r.Context().Done():
+				return
+			}
+			if !first.forgotten {
+				writeRecorded(w, first, true)
+				return
+			}
+			// The first attempt failed and released the key: try to claim it again.
+		}
+	})
+}
+
+// serveFirst runs the handler for the request that claimed key and records the
+// outcome for the retries waiting on rec.
+func serveFirst(w http.ResponseWriter, r *http.Request, store *WindowStore[string, *recordedResponse], key string, rec *recordedResponse, next http.Handler) {
+	buf := &responseRecorder{header: make(http.Header)}
+	defer func() {
+		if p := recover(); p != nil {
+			store.Forget(key)
+			rec.forgotten = true
+			close(rec.done)
+			panic(p)
+		}
+	}()
+	next.ServeHTTP(buf, r)
+
+	rec.status = cmp.Or(buf.status, http.StatusOK)
+	rec.header = buf.header
+	rec.body = buf.body.Bytes()
+	if rec.status >= 500 {
+		store.Forget(key)
+		rec.forgotten = true
+	} else {
+		store.Unpin(key)
+	}
+	close(rec.done)
+
+	writeRecorded(w, rec, false)
+}
+
+func writeRecorded(w http.ResponseWriter, rec *recordedResponse, replayed bool) {
+	maps.Copy(w.Header(), rec.header)
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	w.WriteHeader(rec.status)
+	w.Write(rec.body)
+}
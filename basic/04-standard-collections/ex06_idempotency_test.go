@@ -0,0 +1,284 @@
+package collections
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCreateSignatureIsCanonical(t *testing.T) {
+	base := CreateSignature("POST", "/v1/charges", []string{"Content-Type: application/json", "X-Tenant: acme"})
+	tests := []struct {
+		name    string
+		sig     RequestSignature
+		isEqual bool
+	}{
+		{"same request", CreateSignature("POST", "/v1/charges", []string{"Content-Type: application/json", "X-Tenant: acme"}), true},
+		{"header order, case and spacing", CreateSignature("post", "v1//charges/", []string{"x-tenant:   acme", "content-type: application/json"}), true},
+		{"different header value", CreateSignature("POST", "/v1/charges", []string{"Content-Type: application/json", "X-Tenant: globex"}), false},
+		{"different path", CreateSignature("POST", "/v1/refunds", []string{"Content-Type: application/json", "X-Tenant: acme"}), false},
+		{"body included", base.WithBody([]byte(`{"amount":100}`)), false},
+	}
+	for _, tt := range tests {
+		if (tt.sig == base) != tt.isEqual {
+			t.Errorf("%s: Expected equal=%v for %+v vs %+v", tt.name, tt.isEqual, tt.sig, base)
+		}
+	}
+}
+
+func TestIsDuplicateWithinWindow(t *testing.T) {
+	ResetCache()
+	defer ResetCache()
+
+	now := time.Unix(0, 0)
+	seenRequests.now = func() time.Time { return now }
+
+	sig := CreateSignature("POST", "/webhook", []string{"X-Event: charge.succeeded"})
+	if IsDuplicate(sig) {
+		t.Fatalf("Expected the first request not to be a duplicate")
+	}
+	if !IsDuplicate(sig) {
+		t.Fatalf("Expected an immediate retry to be a duplicate")
+	}
+
+	now = now.Add(DedupWindow)
+	if IsDuplicate(sig) {
+		t.Fatalf("Expected the signature to be forgotten after %v", DedupWindow)
+	}
+}
+
+func TestWindowStoreBoundsAndExpiry(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewWindowStore[string, int](time.Minute, 2)
+	s.now = func() time.Time { return now }
+
+	if _, ok := s.Claim("a", 1); !ok {
+		t.Fatalf("Expected the first claim to win")
+	}
+	if v, ok := s.Claim("a", 2); ok || v != 1 {
+		t.Fatalf("Expected the second claim to return the first value, got %d, %v", v, ok)
+	}
+
+	now = now.Add(30 * time.Second)
+	s.Claim("b", 2)
+	s.Claim("c", 3) // Full: evicts "a", the oldest claim.
+	if _, ok := s.Claim("a", 4); !ok {
+		t.Errorf("Expected %q to have been evicted to respect the bound", "a")
+	}
+	if s.Len() != 2 {
+		t.Errorf("Expected 2 keys, got %d", s.Len())
+	}
+
+	now = now.Add(time.Minute)
+	if s.Len() != 0 {
+		t.Errorf("Expected every key to expire after the window, got %d", s.Len())
+	}
+
+	s.Claim("d", 5)
+	s.Forget("d")
+	if _, ok := s.Claim("d", 6); !ok {
+		t.Errorf("Expected a forgotten key to be claimable again")
+	}
+}
+
+func TestWindowStorePinnedClaims(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewWindowStore[string, int](time.Minute, 1)
+	s.now = func() time.Time { return now }
+
+	s.ClaimPinned("a", 1)
+	now = now.Add(time.Hour)
+	s.Claim("b", 2) // Full, but the only other key is pinned.
+	if v, ok := s.Claim("a", 3); ok || v != 1 {
+		t.Fatalf("Expected a pinned claim to survive the window and the bound, got %d, %v", v, ok)
+	}
+
+	s.Unpin("a")
+	now = now.Add(30 * time.Second)
+	if _, ok := s.Claim("a", 4); ok {
+		t.Errorf("Expected the window to start at Unpin")
+	}
+	now = now.Add(time.Minute)
+	if _, ok := s.Claim("a", 5); !ok {
+		t.Errorf("Expected an unpinned claim to expire after the window")
+	}
+}
+
+func chargeHandler(calls *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"charge":%d}`, n)
+	})
+}
+
+func postCharge(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/charges", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIdempotencyMiddlewareReplays(t *testing.T) {
+	var calls atomic.Int32
+	h := IdempotencyMiddleware(time.Hour, 100, chargeHandler(&calls))
+
+	first := postCharge(h, "key-1", `{"amount":100}`)
+	retry := postCharge(h, "key-1", `{"amount":100}`)
+
+	if calls.Load() != 1 {
+		t.Fatalf("Expected the handler to run once, ran %d times", calls.Load())
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected the retry to replay %d %s, got %d %s", first.Code, first.Body, retry.Code, retry.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected only the replay to carry Idempotent-Replayed")
+	}
+	if retry.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected recorded headers to be replayed")
+	}
+
+	if rr := postCharge(h, "key-1", `{"amount":999}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a reused key with a different body, got %d", rr.Code)
+	}
+
+	postCharge(h, "", `{"amount":100}`)
+	postCharge(h, "key-2", `{"amount":100}`)
+	if calls.Load() != 3 {
+		t.Errorf("Expected requests without a key or with a new key to run, got %d calls", calls.Load())
+	}
+}
+
+func TestIdempotencyMiddlewareConcurrentRetries(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		chargeHandler(&calls).ServeHTTP(w, r)
+	})
+	h := IdempotencyMiddleware(time.Hour, 100, slow)
+
+	results := make([]*httptest.ResponseRecorder, 5)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = postCharge(h, "key-1", `{"amount":100}`)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("Expected concurrent retries to run the handler once, ran %d times", calls.Load())
+	}
+	for i, rr := range results {
+		if rr.Code != http.StatusCreated || rr.Body.String() != `{"charge":1}` {
+			t.Errorf("request %d: Expected the first response, got %d %s", i, rr.Code, rr.Body)
+		}
+	}
+}
+
+func TestIdempotencyMiddlewareRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	h := IdempotencyMiddleware(time.Hour, 100, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, "database unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	if rr := postCharge(h, "key-1", "{}"); rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d", rr.Code)
+	}
+	if rr := postCharge(h, "key-1", "{}"); rr.Code != http.StatusCreated {
+		t.Errorf("Expected the retry of a 5xx to run again, got %d", rr.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareLimitsBody(t *testing.T) {
+	var calls atomic.Int32
+	h := IdempotencyMiddlewareWithLimit(time.Hour, 100, 16, chargeHandler(&calls))
+
+	if rr := postCharge(h, "key-big", `{"amount":100000000}`); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a body over the limit, got %d", rr.Code)
+	}
+	if rr := postCharge(h, "key-big", `{"amount":1}`); rr.Code != http.StatusCreated || calls.Load() != 1 {
+		t.Errorf("Expected a rejected body not to claim its key, got %d after %d calls", rr.Code, calls.Load())
+	}
+}
+
+func TestIdempotencyMiddlewarePinsInFlightRequests(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Idempotency-Key") == "key-1" {
+			close(started) // Panics if key-1 runs twice.
+			<-release
+		}
+		chargeHandler(&calls).ServeHTTP(w, r)
+	})
+	// The window and the bound are both far smaller than the handler's run time.
+	h := IdempotencyMiddleware(time.Millisecond, 1, slow)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postCharge(h, "key-1", "{}") }()
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	postCharge(h, "key-other", "{}") // Would evict key-1 if it were not pinned.
+	go func() { done <- postCharge(h, "key-1", "{}") }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	for range 2 {
+		if rr := <-done; rr.Body.String() != `{"charge":2}` {
+			t.Errorf("Expected both requests to get the first response, got %d %s", rr.Code, rr.Body)
+		}
+	}
+	if calls.Load() != 2 { // key-other, then key-1 once
+		t.Errorf("Expected key-1 to run once, got %d calls in total", calls.Load())
+	}
+}
+
+func TestIdempotencyMiddlewareWaitersRetryAfterServerError(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	h := IdempotencyMiddleware(time.Hour, 100, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-release
+			http.Error(w, "database unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- postCharge(h, "key-1", "{}") }()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	waiter := make(chan *httptest.ResponseRecorder)
+	go func() { waiter <- postCharge(h, "key-1", "{}") }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if rr := <-first; rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the first request to get 503, got %d", rr.Code)
+	}
+	if rr := <-waiter; rr.Code != http.StatusCreated || rr.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected the waiting retry to run afresh, got %d", rr.Code)
+	}
+}
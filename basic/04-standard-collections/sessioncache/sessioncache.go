@@ -0,0 +1,417 @@
+// Package sessioncache is a bounded, concurrent in-memory cache for session-like data.
+//
+// A single mutex around one map serializes every request in the process. The cache
+// instead hashes each key to one of N shards, each with its own lock, map and
+// eviction state, so unrelated keys never contend.
+//
+// Every shard enforces its share of MaxEntries and evicts with one of two policies:
+//
+//   - LRU evicts the least recently used entry. Exact, but every hit moves the entry
+//     to the front of a list, so Get takes the shard's write lock.
+//   - Clock approximates LRU with a "referenced" bit per entry and a sweeping hand.
+//     A hit only sets the bit, so Get runs under the read lock and scales with readers.
+//
+// Entries can carry a TTL. Expired entries are never returned; they are removed when
+// touched, when the eviction policy reaches them, or by DeleteExpired.
+package sessioncache
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policy selects how a full shard picks its victim.
+type Policy int
+
+const (
+	LRU Policy = iota
+	Clock
+)
+
+// EvictReason tells an OnEvict callback why an entry left the cache.
+type EvictReason int
+
+const (
+	EvictCapacity EvictReason = iota // Shard was full
+	EvictExpired                     // TTL elapsed
+	EvictDeleted                     // Explicit Delete
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictExpired:
+		return "expired"
+	case EvictDeleted:
+		return "deleted"
+	default:
+		return fmt.Sprintf("EvictReason(%d)", int(r))
+	}
+}
+
+// Options configures a Cache. The zero value is an unbounded LRU cache with 16 shards
+// and no expiry.
+type Options[K comparable, V any] struct {
+	Shards     int           // Number of shards; default 16, never more than MaxEntries
+	MaxEntries int           // Total bound across all shards; 0 means unbounded
+	TTL        time.Duration // Default lifetime used by Set; 0 means entries never expire
+	Policy     Policy
+
+	// OnEvict is called after an entry is removed, outside any lock, so it may call
+	// back into the cache. It is not called when Set overwrites an existing key.
+	OnEvict func(key K, value V, reason EvictReason)
+
+	Now func() time.Time // Time source; defaults to time.Now
+}
+
+// Stats are cumulative counters since the cache was created.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64 // Includes lookups that found an expired entry
+	Evictions   uint64 // Entries removed to make room
+	Expirations uint64 // Expired entries removed
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time // Zero means never
+
+	prev, next *entry[K, V] // LRU list
+	slot       int          // Clock ring position
+	ref        atomic.Bool  // Clock "recently used" bit, set under the read lock
+}
+
+func (e *entry[K, V]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+type shard[K comparable, V any] struct {
+	mu    sync.RWMutex
+	items map[K]*entry[K, V]
+	max   int // 0 means unbounded
+
+	lru  entry[K, V] // Sentinel: lru.next is the most recently used entry
+	ring []*entry[K, V]
+	hand int
+
+	hits, misses, evictions, expirations atomic.Uint64
+}
+
+// removal is an eviction waiting for its OnEvict call.
+type removal[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+// Cache is a sharded, bounded cache safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	shards  []*shard[K, V]
+	seed    maphash.Seed
+	policy  Policy
+	ttl     time.Duration
+	onEvict func(K, V, EvictReason)
+	now     func() time.Time
+}
+
+// New returns an empty cache configured by opts.
+func New[K comparable, V any](opts Options[K, V]) *Cache[K, V] {
+	n := opts.Shards
+	if n <= 0 {
+		n = 16
+	}
+	if opts.MaxEntries > 0 {
+		n = min(n, opts.MaxEntries) // Every shard must be able to hold at least one entry.
+	}
+
+	c := &Cache[K, V]{
+		shards:  make([]*shard[K, V], n),
+		seed:    maphash.MakeSeed(),
+		policy:  opts.Policy,
+		ttl:     opts.TTL,
+		onEvict: opts.OnEvict,
+		now:     opts.Now,
+	}
+	if c.now == nil {
+		c.now = time.Now
+	}
+	for i := range c.shards {
+		s := &shard[K, V]{items: make(map[K]*entry[K, V])}
+		if opts.MaxEntries > 0 {
+			// Spread the remainder so the shard bounds add up to exactly MaxEntries.
+			s.max = opts.MaxEntries / n
+			if i < opts.MaxEntries%n {
+				s.max++
+			}
+		}
+		s.lru.next, s.lru.prev = &s.lru, &s.lru
+		c.shards[i] = s
+	}
+	return c
+}
+
+func (c *Cache[K, V]) shardFor(key K) *shard[K, V] {
+	return c.shards[maphash.Comparable(c.seed, key)%uint64(len(c.shards))]
+}
+
+// Get returns the value stored under key if it is present and not expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	s := c.shardFor(key)
+	now := c.now()
+	var zero V
+
+	if c.policy == Clock {
+		s.mu.RLock()
+		e, ok := s.items[key]
+		if !ok {
+			s.mu.RUnlock()
+			s.misses.Add(1)
+			return zero, false
+		}
+		if e.expired(now) {
+			// Upgrade to the write lock to remove it, as the LRU path does. Another
+			// goroutine may have replaced or removed the entry in between.
+			s.mu.RUnlock()
+			s.mu.Lock()
+			expired := s.items[key] == e && e.expired(now)
+			if expired {
+				c.remove(s, e)
+				s.expirations.Add(1)
+			}
+			s.mu.Unlock()
+			s.misses.Add(1)
+			if expired {
+				c.notify(removal[K, V]{e.key, e.value, EvictExpired})
+			}
+			return zero, false
+		}
+		e.ref.Store(true)
+		v := e.value
+		s.mu.RUnlock()
+		s.hits.Add(1)
+		return v, true
+	}
+
+	s.mu.Lock()
+	e, ok := s.items[key]
+	if !ok {
+		s.mu.Unlock()
+		s.misses.Add(1)
+		return zero, false
+	}
+	if e.expired(now) {
+		c.remove(s, e)
+		s.expirations.Add(1)
+		s.mu.Unlock()
+		s.misses.Add(1)
+		c.notify(removal[K, V]{e.key, e.value, EvictExpired})
+		return zero, false
+	}
+	c.touch(s, e)
+	v := e.value
+	s.mu.Unlock()
+	s.hits.Add(1)
+	return v, true
+}
+
+// Set stores value under key with the cache's default TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL stores value under key for ttl; ttl <= 0 means it never expires. If the
+// key's shard is full, the policy evicts an entry first.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	s := c.shardFor(key)
+	now := c.now()
+	var expires time.Time
+	if ttl > 0 {
+		expires = now.Add(ttl)
+	}
+
+	s.mu.Lock()
+	if e, ok := s.items[key]; ok {
+		e.value, e.expires = value, expires
+		c.touch(s, e)
+		s.mu.Unlock()
+		return
+	}
+
+	var removed []removal[K, V]
+	for s.max > 0 && len(s.items) >= s.max {
+		victim := c.victim(s, now)
+		reason := EvictCapacity
+		if victim.expired(now) {
+			reason = EvictExpired
+			s.expirations.Add(1)
+		} else {
+			s.evictions.Add(1)
+		}
+		c.remove(s, victim)
+		removed = append(removed, removal[K, V]{victim.key, victim.value, reason})
+	}
+
+	e := &entry[K, V]{key: key, value: value, expires: expires}
+	s.items[key] = e
+	c.insert(s, e)
+	s.mu.Unlock()
+
+	c.notify(removed...)
+}
+
+// Delete removes key and reports whether it was present.
+func (c *Cache[K, V]) Delete(key K) bool {
+	s := c.shardFor(key)
+
+	s.mu.Lock()
+	e, ok := s.items[key]
+	if ok {
+		c.remove(s, e)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		c.notify(removal[K, V]{e.key, e.value, EvictDeleted})
+	}
+	return ok
+}
+
+// DeleteExpired removes every expired entry and returns how many it removed. Call it
+// periodically if expired entries should not wait for eviction to reclaim them.
+func (c *Cache[K, V]) DeleteExpired() int {
+	now := c.now()
+	total := 0
+	for _, s := range c.shards {
+		var removed []removal[K, V]
+		s.mu.Lock()
+		for _, e := range s.items {
+			if e.expired(now) {
+				c.remove(s, e)
+				removed = append(removed, removal[K, V]{e.key, e.value, EvictExpired})
+			}
+		}
+		s.expirations.Add(uint64(len(removed)))
+		s.mu.Unlock()
+
+		c.notify(removed...)
+		total += len(removed)
+	}
+	return total
+}
+
+// Range calls fn for every live entry until fn returns false. Each shard is
+// snapshotted under its lock and fn runs without holding any lock, so fn may use the
+// cache. Entries added or removed concurrently may or may not be visited.
+func (c *Cache[K, V]) Range(fn func(key K, value V) bool) {
+	now := c.now()
+	for _, s := range c.shards {
+		s.mu.RLock()
+		snapshot := make([]removal[K, V], 0, len(s.items))
+		for _, e := range s.items {
+			if !e.expired(now) {
+				snapshot = append(snapshot, removal[K, V]{key: e.key, value: e.value})
+			}
+		}
+		s.mu.RUnlock()
+
+		for _, kv := range snapshot {
+			if !fn(kv.key, kv.value) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of stored entries, including expired entries that have not
+// been removed yet.
+func (c *Cache[K, V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.RLock()
+		n += len(s.items)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Stats returns the counters summed over all shards.
+func (c *Cache[K, V]) Stats() Stats {
+	var st Stats
+	for _, s := range c.shards {
+		st.Hits += s.hits.Load()
+		st.Misses += s.misses.Load()
+		st.Evictions += s.evictions.Load()
+		st.Expirations += s.expirations.Load()
+	}
+	return st
+}
+
+func (c *Cache[K, V]) notify(removed ...removal[K, V]) {
+	if c.onEvict == nil {
+		return
+	}
+	for _, r := range removed {
+		c.onEvict(r.key, r.value, r.reason)
+	}
+}
+
+// The helpers below require the shard's write lock.
+
+func (c *Cache[K, V]) insert(s *shard[K, V], e *entry[K, V]) {
+	if c.policy == Clock {
+		e.slot = len(s.ring)
+		s.ring = append(s.ring, e)
+		return
+	}
+	e.prev, e.next = &s.lru, s.lru.next
+	s.lru.next.prev = e
+	s.lru.next = e
+}
+
+func (c *Cache[K, V]) touch(s *shard[K, V], e *entry[K, V]) {
+	if c.policy == Clock {
+		e.ref.Store(true)
+		return
+	}
+	e.prev.next, e.next.prev = e.next, e.prev
+	c.insert(s, e)
+}
+
+func (c *Cache[K, V]) remove(s *shard[K, V], e *entry[K, V]) {
+	delete(s.items, e.key)
+	if c.policy == Clock {
+		// Swap-remove: the clock only needs a ring, not a particular order.
+		last := len(s.ring) - 1
+		s.ring[e.slot] = s.ring[last]
+		s.ring[e.slot].slot = e.slot
+		s.ring[last] = nil
+		s.ring = s.ring[:last]
+		if s.hand >= len(s.ring) {
+			s.hand = 0
+		}
+		return
+	}
+	e.prev.next, e.next.prev = e.next, e.prev
+	e.prev, e.next = nil, nil
+}
+
+// victim picks the entry to evict from a non-empty shard. Expired entries go first
+// under Clock; LRU takes the least recently used entry.
+func (c *Cache[K, V]) victim(s *shard[K, V], now time.Time) *entry[K, V] {
+	if c.policy != Clock {
+		return s.lru.prev
+	}
+	// Every referenced entry loses its bit on the first lap, so this ends within two.
+	for {
+		e := s.ring[s.hand]
+		if e.expired(now) || !e.ref.Swap(false) {
+			return e
+		}
+		s.hand = (s.hand + 1) % len(s.ring)
+	}
+}
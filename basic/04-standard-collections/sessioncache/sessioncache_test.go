@@ -0,0 +1,193 @@
+package sessioncache
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNow is a manually advanced time source.
+type fakeNow struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (f *fakeNow) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.t
+}
+
+func (f *fakeNow) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.t = f.t.Add(d)
+}
+
+type eviction struct {
+	key    string
+	reason EvictReason
+}
+
+func newRecorded(opts Options[string, int]) (*Cache[string, int], *[]eviction) {
+	var mu sync.Mutex
+	var got []eviction
+	opts.OnEvict = func(key string, _ int, reason EvictReason) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, eviction{key, reason})
+	}
+	return New(opts), &got
+}
+
+func TestEvictionPolicies(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		victim string
+	}{
+		// "a" was read after "b" was written, so LRU evicts "b".
+		{LRU, "b"},
+		// The clock hand starts at "a", whose referenced bit spares it; "b" has none.
+		{Clock, "b"},
+	}
+	for _, tt := range tests {
+		c, evicted := newRecorded(Options[string, int]{Shards: 1, MaxEntries: 3, Policy: tt.policy})
+		c.Set("a", 1)
+		c.Set("b", 2)
+		c.Set("c", 3)
+		c.Get("a")
+		c.Set("d", 4)
+
+		want := []eviction{{tt.victim, EvictCapacity}}
+		if !slices.Equal(*evicted, want) {
+			t.Errorf("policy %d: Expected evictions %v, got %v", tt.policy, want, *evicted)
+		}
+		if c.Len() != 3 {
+			t.Errorf("policy %d: Expected 3 entries, got %d", tt.policy, c.Len())
+		}
+		if _, ok := c.Get("a"); !ok {
+			t.Errorf("policy %d: Expected recently used %q to survive", tt.policy, "a")
+		}
+		if st := c.Stats(); st.Evictions != 1 {
+			t.Errorf("policy %d: Expected 1 eviction, got %+v", tt.policy, st)
+		}
+	}
+}
+
+func TestMaxEntriesIsExactAcrossShards(t *testing.T) {
+	c := New(Options[string, int]{Shards: 8, MaxEntries: 100})
+	for i := range 10_000 {
+		c.Set(strconv.Itoa(i), i)
+	}
+	if n := c.Len(); n > 100 {
+		t.Errorf("Expected at most 100 entries, got %d", n)
+	}
+
+	small := New(Options[string, int]{Shards: 16, MaxEntries: 2})
+	small.Set("x", 1)
+	if _, ok := small.Get("x"); !ok {
+		t.Errorf("Expected a cache with fewer entries than shards to still store values")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	for _, policy := range []Policy{LRU, Clock} {
+		clock := &fakeNow{t: time.Unix(0, 0)}
+		c, evicted := newRecorded(Options[string, int]{TTL: time.Minute, Policy: policy, Now: clock.Now})
+
+		c.Set("short", 1)
+		c.SetWithTTL("long", 2, time.Hour)
+		c.SetWithTTL("forever", 3, 0)
+
+		clock.Advance(2 * time.Minute)
+		if _, ok := c.Get("short"); ok {
+			t.Errorf("policy %d: Expected %q to have expired", policy, "short")
+		}
+		// Touching an expired entry removes it under either policy.
+		if c.Len() != 2 || !slices.Contains(*evicted, eviction{"short", EvictExpired}) {
+			t.Errorf("policy %d: Expected Get to remove %q, got len %d and %v", policy, "short", c.Len(), *evicted)
+		}
+		for _, key := range []string{"long", "forever"} {
+			if _, ok := c.Get(key); !ok {
+				t.Errorf("policy %d: Expected %q to be live", policy, key)
+			}
+		}
+
+		var keys []string
+		c.Range(func(k string, _ int) bool {
+			keys = append(keys, k)
+			return true
+		})
+		slices.Sort(keys)
+		if !slices.Equal(keys, []string{"forever", "long"}) {
+			t.Errorf("policy %d: Expected Range to skip expired entries, got %v", policy, keys)
+		}
+
+		c.DeleteExpired()
+		if c.Len() != 2 {
+			t.Errorf("policy %d: Expected 2 entries after DeleteExpired, got %d", policy, c.Len())
+		}
+		if !slices.Contains(*evicted, eviction{"short", EvictExpired}) {
+			t.Errorf("policy %d: Expected an expiry callback for %q, got %v", policy, "short", *evicted)
+		}
+		if st := c.Stats(); st.Expirations != 1 || st.Hits != 2 || st.Misses != 1 {
+			t.Errorf("policy %d: Unexpected stats %+v", policy, st)
+		}
+	}
+}
+
+func TestDeleteAndCallbacksMayReenter(t *testing.T) {
+	var c *Cache[string, int]
+	c = New(Options[string, int]{
+		Shards:     1,
+		MaxEntries: 1,
+		OnEvict: func(key string, value int, reason EvictReason) {
+			// Callbacks run outside the shard lock, so re-entering must not deadlock.
+			if reason == EvictCapacity {
+				c.Get(key)
+			}
+		},
+	})
+
+	c.Set("a", 1)
+	c.Set("b", 2) // Evicts "a" and calls back into the cache.
+	if !c.Delete("b") || c.Delete("b") {
+		t.Errorf("Expected Delete to report presence exactly once")
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	for _, policy := range []Policy{LRU, Clock} {
+		c := New(Options[string, int]{MaxEntries: 64, Policy: policy, TTL: time.Hour})
+		var wg sync.WaitGroup
+		for g := range 16 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 1000 {
+					key := fmt.Sprintf("k%d", (g*31+i)%200)
+					switch i % 4 {
+					case 0:
+						c.Set(key, i)
+					case 1:
+						c.Delete(key)
+					default:
+						c.Get(key)
+					}
+				}
+				c.Range(func(string, int) bool { return true })
+			}()
+		}
+		wg.Wait()
+
+		if n := c.Len(); n > 64 {
+			t.Errorf("policy %d: Expected at most 64 entries, got %d", policy, n)
+		}
+		if st := c.Stats(); st.Hits+st.Misses != 16*500 {
+			t.Errorf("policy %d: Expected %d lookups, got %+v", policy, 16*500, st)
+		}
+	}
+}
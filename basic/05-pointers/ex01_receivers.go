@@ -17,15 +17,17 @@ import "sync"
 // 2. `Deposit` must add the amount to the balance, entirely safely.
 // 3. Fix the receiver types on both methods.
 
+// Account is a balance guarded by its own mutex. It must always be used through a
+// pointer. `Ledger` (ex03_ledger.go) builds journaled transfers and holds on top of it.
 type Account struct {
 	mu      sync.Mutex
+	ID      AccountID
 	Balance int
+	Held    int // Amount reserved by open authorization holds; only the Ledger sets it
 }
 
-// BUG: Value receiver copies the struct (and the mutex).
-// Any lock acquired here only locks the copy. Any mutation to Balance only mutates the copy.
-// TODO: Fix the receiver type so mutations apply to the actual account, and locks work.
-func (a Account) Withdraw(amount int) {
+// Withdraw subtracts amount if the balance covers it.
+func (a *Account) Withdraw(amount int) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -34,9 +36,8 @@ func (a Account) Withdraw(amount int) {
 	}
 }
 
-// BUG: Same as above.
-// TODO: Fix the receiver.
-func (a Account) Deposit(amount int) {
+// Deposit adds amount to the balance.
+func (a *Account) Deposit(amount int) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
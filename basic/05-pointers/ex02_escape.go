@@ -17,35 +17,40 @@ package pointers
 type Event struct {
 	ID      string
 	Payload string
+	Seq     uint64 // Assigned by EventStream on Commit
 }
 
-// BUG: Returning a pointer forces this small struct onto the heap.
-// At 50,000 requests per second, this creates massive GC pressure.
-// TODO: Refactor `CreateEvent` to return `Event` (the value), not `*Event`.
-func CreateEvent(id, payload string) *Event {
-	e := Event{
+// CreateEvent returns the event by value, so it stays on the caller's stack.
+func CreateEvent(id, payload string) Event {
+	return Event{
 		ID:      id,
 		Payload: payload,
 	}
-	return &e // Pointers to local variables escape to the heap!
 }
 
-// TODO: Refactor this processing function to accept the Value, or take the address of the returned value.
+// legacySink adapts legacyEmit to EventSink. Taking the address of the parameter is
+// safe: legacyEmit does not retain it, so the copy stays on the stack.
+type legacySink struct {
+	emitted int
+}
+
+func (s *legacySink) Consume(e Event) {
+	legacyEmit(&e)
+	s.emitted++
+}
+
+// ProcessStream fills pre-allocated events in a ring (see ex04_event_stream.go)
+// instead of creating one per iteration.
 func ProcessStream() int {
-	eventsProcessed := 0
+	sink := &legacySink{}
+	stream := NewEventStream(256, sink)
 
 	for i := 0; i < 1000; i++ {
-		// If you changed CreateEvent to return a value, this next line will error!
-		evt := CreateEvent("id-123", "data")
-
-		// Simulate doing something that requires pointer access, e.g. a legacy func
-		// that we can't change. You can take the address of `evt` safely here because
-		// it's contained inside this loop's stack frame.
-		legacyEmit(evt)
-		eventsProcessed++
+		stream.Emit("id-123", "data")
 	}
+	stream.Flush()
 
-	return eventsProcessed
+	return sink.emitted
 }
 
 // Simulate a legacy function we cannot change.
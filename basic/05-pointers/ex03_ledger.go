@@ -0,0 +1,399 @@
+package pointers
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Context: A Journaled In-Memory Ledger
+// The payments sandbox needs more than `Withdraw`/`Deposit` on one account: money moves
+// *between* accounts, card authorizations reserve funds before they are captured, and
+// auditors want to prove that no money was created or destroyed.
+//
+// Why this matters: Every `*Account` keeps its own mutex, and a transfer needs two of
+// them. If one goroutine locks A then B while another locks B then A, both wait
+// forever. The ledger always locks accounts in ascending ID order, so no cycle can
+// form. The pointer-receiver lesson still applies: the ledger holds `*Account` values
+// and every lock and mutation goes through those pointers.
+//
+// Design:
+// 1. Money enters and leaves through `ExternalAccount`, the only account allowed to go
+//    negative, so the sum of all balances is always exactly zero.
+// 2. Every movement appends an immutable `JournalEntry` while the affected accounts are
+//    locked, so the journal order matches the order balances changed.
+// 3. `Reconstruct` replays a journal into balances; `Check` compares the replay with the
+//    live accounts and verifies the conservation invariant.
+
+// AccountID identifies an account in a Ledger.
+type AccountID string
+
+// ExternalAccount is the counterparty for deposits and withdrawals.
+const ExternalAccount AccountID = "external"
+
+// HoldID identifies an authorization hold.
+type HoldID uint64
+
+var (
+	ErrUnknownAccount     = errors.New("unknown account")
+	ErrAccountExists      = errors.New("account already exists")
+	ErrInvalidAmount      = errors.New("amount must be positive")
+	ErrSameAccount        = errors.New("cannot transfer to the same account")
+	ErrInsufficientFunds  = errors.New("insufficient available funds")
+	ErrUnknownHold        = errors.New("unknown hold")
+	ErrHoldClosed         = errors.New("hold already captured or released")
+	ErrInvariantViolation = errors.New("ledger invariant violated")
+)
+
+// EntryKind is the type of a journal entry.
+type EntryKind int
+
+const (
+	EntryTransfer EntryKind = iota // Amount moves From -> To
+	EntryHold                      // Amount of From is reserved
+	EntryCapture                   // Held amount moves From -> To
+	EntryRelease                   // Reserved amount of From is freed
+)
+
+func (k EntryKind) String() string {
+	switch k {
+	case EntryTransfer:
+		return "transfer"
+	case EntryHold:
+		return "hold"
+	case EntryCapture:
+		return "capture"
+	case EntryRelease:
+		return "release"
+	default:
+		return fmt.Sprintf("EntryKind(%d)", int(k))
+	}
+}
+
+// JournalEntry is one immutable ledger movement.
+type JournalEntry struct {
+	Seq    int
+	Time   time.Time
+	Kind   EntryKind
+	From   AccountID
+	To     AccountID // Empty for holds and releases
+	Amount int
+	Hold   HoldID // Set for hold, capture and release entries
+}
+
+// AccountState is the balance of one account as of some point in the journal.
+type AccountState struct {
+	Balance int
+	Held    int
+}
+
+// Available returns the balance not reserved by holds.
+func (s AccountState) Available() int {
+	return s.Balance - s.Held
+}
+
+type hold struct {
+	id      HoldID
+	account *Account
+	amount  int
+	open    bool // Guarded by account.mu
+}
+
+// Ledger is a set of accounts whose every movement is journaled. It is safe for
+// concurrent use. Locks are always taken in this order: ledger, accounts by ascending
+// ID, journal.
+type Ledger struct {
+	mu       sync.RWMutex // Guards accounts, holds and nextHold
+	accounts map[AccountID]*Account
+	holds    map[HoldID]*hold
+	nextHold HoldID
+
+	journalMu sync.Mutex
+	journal   []JournalEntry
+}
+
+// NewLedger returns a ledger containing only ExternalAccount.
+func NewLedger() *Ledger {
+	return &Ledger{
+		accounts: map[AccountID]*Account{ExternalAccount: {ID: ExternalAccount}},
+		holds:    make(map[HoldID]*hold),
+	}
+}
+
+// Open creates an empty account.
+func (l *Ledger) Open(id AccountID) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.accounts[id]; ok {
+		return fmt.Errorf("%w: %s", ErrAccountExists, id)
+	}
+	l.accounts[id] = &Account{ID: id}
+	return nil
+}
+
+func (l *Ledger) account(id AccountID) (*Account, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	a, ok := l.accounts[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, id)
+	}
+	return a, nil
+}
+
+// lockAccounts locks accounts in ascending ID order and returns the matching unlock.
+func lockAccounts(accounts ...*Account) (unlock func()) {
+	sorted := slices.SortedFunc(slices.Values(accounts), func(a, b *Account) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	sorted = slices.Compact(sorted)
+	for _, a := range sorted {
+		a.mu.Lock()
+	}
+	return func() {
+		for _, a := range slices.Backward(sorted) {
+			a.mu.Unlock()
+		}
+	}
+}
+
+// record appends an entry. The caller must hold the locks of the accounts it touches.
+func (l *Ledger) record(e JournalEntry) JournalEntry {
+	l.journalMu.Lock()
+	defer l.journalMu.Unlock()
+	e.Seq = len(l.journal)
+	e.Time = time.Now()
+	l.journal = append(l.journal, e)
+	return e
+}
+
+// Deposit moves amount from ExternalAccount into id.
+func (l *Ledger) Deposit(id AccountID, amount int) (JournalEntry, error) {
+	return l.Transfer(ExternalAccount, id, amount)
+}
+
+// Withdraw moves amount from id to ExternalAccount.
+func (l *Ledger) Withdraw(id AccountID, amount int) (JournalEntry, error) {
+	return l.Transfer(id, ExternalAccount, amount)
+}
+
+// Transfer atomically moves amount between two accounts. The source must have enough
+// available (unheld) funds unless it is ExternalAccount.
+func (l *Ledger) Transfer(from, to AccountID, amount int) (JournalEntry, error) {
+	if amount <= 0 {
+		return JournalEntry{}, fmt.Errorf("%w: %d", ErrInvalidAmount, amount)
+	}
+	if from == to {
+		return JournalEntry{}, fmt.Errorf("%w: %s", ErrSameAccount, from)
+	}
+	src, err := l.account(from)
+	if err != nil {
+		return JournalEntry{}, err
+	}
+	dst, err := l.account(to)
+	if err != nil {
+		return JournalEntry{}, err
+	}
+
+	unlock := lockAccounts(src, dst)
+	defer unlock()
+
+	if from != ExternalAccount && src.Balance-src.Held < amount {
+		return JournalEntry{}, fmt.Errorf("%w: %s has %d available, needs %d", ErrInsufficientFunds, from, src.Balance-src.Held, amount)
+	}
+	src.Balance -= amount
+	dst.Balance += amount
+	return l.record(JournalEntry{Kind: EntryTransfer, From: from, To: to, Amount: amount}), nil
+}
+
+// Authorize reserves amount of id's available funds until the hold is captured or
+// released.
+func (l *Ledger) Authorize(id AccountID, amount int) (HoldID, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidAmount, amount)
+	}
+	a, err := l.account(id)
+	if err != nil {
+		return 0, err
+	}
+
+	// Register the hold before touching the account: taking the ledger lock while
+	// holding an account lock would invert the lock order.
+	l.mu.Lock()
+	l.nextHold++
+	h := &hold{id: l.nextHold, account: a, amount: amount}
+	l.holds[h.id] = h
+	l.mu.Unlock()
+
+	unlock := lockAccounts(a)
+	if a.Balance-a.Held < amount {
+		available := a.Balance - a.Held
+		unlock()
+		l.mu.Lock()
+		delete(l.holds, h.id)
+		l.mu.Unlock()
+		return 0, fmt.Errorf("%w: %s has %d available, needs %d", ErrInsufficientFunds, id, available, amount)
+	}
+	a.Held += amount
+	h.open = true
+	l.record(JournalEntry{Kind: EntryHold, From: id, Amount: amount, Hold: h.id})
+	unlock()
+
+	return h.id, nil
+}
+
+func (l *Ledger) hold(id HoldID) (*hold, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	h, ok := l.holds[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownHold, id)
+	}
+	return h, nil
+}
+
+// Capture moves amount (at most the held amount) of a hold to another account and
+// releases the rest of the hold.
+func (l *Ledger) Capture(id HoldID, to AccountID, amount int) error {
+	h, err := l.hold(id)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 || amount > h.amount {
+		return fmt.Errorf("%w: capture %d of a %d hold", ErrInvalidAmount, amount, h.amount)
+	}
+	if to == h.account.ID {
+		return fmt.Errorf("%w: %s", ErrSameAccount, to)
+	}
+	dst, err := l.account(to)
+	if err != nil {
+		return err
+	}
+
+	unlock := lockAccounts(h.account, dst)
+	defer unlock()
+
+	if !h.open {
+		return fmt.Errorf("%w: %d", ErrHoldClosed, id)
+	}
+	h.open = false
+	h.account.Held -= h.amount
+	h.account.Balance -= amount
+	dst.Balance += amount
+	l.record(JournalEntry{Kind: EntryCapture, From: h.account.ID, To: to, Amount: amount, Hold: id})
+	if rest := h.amount - amount; rest > 0 {
+		l.record(JournalEntry{Kind: EntryRelease, From: h.account.ID, Amount: rest, Hold: id})
+	}
+	return nil
+}
+
+// Release frees a hold without moving any money.
+func (l *Ledger) Release(id HoldID) error {
+	h, err := l.hold(id)
+	if err != nil {
+		return err
+	}
+
+	unlock := lockAccounts(h.account)
+	defer unlock()
+
+	if !h.open {
+		return fmt.Errorf("%w: %d", ErrHoldClosed, id)
+	}
+	h.open = false
+	h.account.Held -= h.amount
+	l.record(JournalEntry{Kind: EntryRelease, From: h.account.ID, Amount: h.amount, Hold: id})
+	return nil
+}
+
+// State returns the current balance and held amount of id.
+func (l *Ledger) State(id AccountID) (AccountState, error) {
+	a, err := l.account(id)
+	if err != nil {
+		return AccountState{}, err
+	}
+	unlock := lockAccounts(a)
+	defer unlock()
+	return AccountState{Balance: a.Balance, Held: a.Held}, nil
+}
+
+// Journal returns a copy of every entry recorded so far, in order.
+func (l *Ledger) Journal() []JournalEntry {
+	l.journalMu.Lock()
+	defer l.journalMu.Unlock()
+	return slices.Clone(l.journal)
+}
+
+// Reconstruct replays journal entries into per-account state. Replaying a prefix of a
+// journal yields the balances as of that point in time.
+func Reconstruct(journal []JournalEntry) map[AccountID]AccountState {
+	states := make(map[AccountID]AccountState)
+	apply := func(id AccountID, balance, held int) {
+		s := states[id]
+		s.Balance += balance
+		s.Held += held
+		states[id] = s
+	}
+
+	for _, e := range journal {
+		switch e.Kind {
+		case EntryTransfer:
+			apply(e.From, -e.Amount, 0)
+			apply(e.To, e.Amount, 0)
+		case EntryHold:
+			apply(e.From, 0, e.Amount)
+		case EntryCapture:
+			apply(e.From, -e.Amount, -e.Amount)
+			apply(e.To, e.Amount, 0)
+		case EntryRelease:
+			apply(e.From, 0, -e.Amount)
+		}
+	}
+	return states
+}
+
+// Check freezes the ledger and verifies that:
+//   - the sum of all balances is zero (money is conserved);
+//   - every account matches the replay of the journal;
+//   - every account's held amount equals its open holds and never exceeds its balance
+//     (except for ExternalAccount, which may be negative).
+func (l *Ledger) Check() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	accounts := slices.Collect(maps.Values(l.accounts))
+	unlock := lockAccounts(accounts...)
+	defer unlock()
+
+	replayed := Reconstruct(l.Journal())
+	openHolds := make(map[AccountID]int)
+	for _, h := range l.holds {
+		if h.open {
+			openHolds[h.account.ID] += h.amount
+		}
+	}
+
+	var errs []error
+	sum := 0
+	for _, a := range accounts {
+		sum += a.Balance
+		live := AccountState{Balance: a.Balance, Held: a.Held}
+		if want := replayed[a.ID]; live != want {
+			errs = append(errs, fmt.Errorf("%w: %s is %+v, journal says %+v", ErrInvariantViolation, a.ID, live, want))
+		}
+		if a.Held != openHolds[a.ID] {
+			errs = append(errs, fmt.Errorf("%w: %s holds %d, open holds total %d", ErrInvariantViolation, a.ID, a.Held, openHolds[a.ID]))
+		}
+		if a.ID != ExternalAccount && live.Available() < 0 {
+			errs = append(errs, fmt.Errorf("%w: %s is overdrawn: %+v", ErrInvariantViolation, a.ID, live))
+		}
+	}
+	if sum != 0 {
+		errs = append(errs, fmt.Errorf("%w: balances sum to %d, want 0", ErrInvariantViolation, sum))
+	}
+	return errors.Join(errs...)
+}
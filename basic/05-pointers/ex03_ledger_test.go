@@ -0,0 +1,209 @@
+package pointers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func newTestLedger(t *testing.T, balances map[AccountID]int) *Ledger {
+	t.Helper()
+	l := NewLedger()
+	for id, amount := range balances {
+		if err := l.Open(id); err != nil {
+			t.Fatalf("Open(%s): %v", id, err)
+		}
+		if amount > 0 {
+			if _, err := l.Deposit(id, amount); err != nil {
+				t.Fatalf("Deposit(%s): %v", id, err)
+			}
+		}
+	}
+	return l
+}
+
+func balanceOf(t *testing.T, l *Ledger, id AccountID) AccountState {
+	t.Helper()
+	s, err := l.State(id)
+	if err != nil {
+		t.Fatalf("State(%s): %v", id, err)
+	}
+	return s
+}
+
+func TestLedgerTransfer(t *testing.T) {
+	l := newTestLedger(t, map[AccountID]int{"alice": 100, "bob": 0})
+
+	tests := []struct {
+		name     string
+		from, to AccountID
+		amount   int
+		wantErr  error
+	}{
+		{"valid transfer", "alice", "bob", 60, nil},
+		{"overdraft", "alice", "bob", 41, ErrInsufficientFunds},
+		{"zero amount", "alice", "bob", 0, ErrInvalidAmount},
+		{"same account", "bob", "bob", 1, ErrSameAccount},
+		{"unknown account", "alice", "carol", 1, ErrUnknownAccount},
+	}
+	for _, tt := range tests {
+		_, err := l.Transfer(tt.from, tt.to, tt.amount)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	if got := balanceOf(t, l, "alice").Balance; got != 40 {
+		t.Errorf("Expected alice to have 40, got %d", got)
+	}
+	if got := balanceOf(t, l, "bob").Balance; got != 60 {
+		t.Errorf("Expected bob to have 60, got %d", got)
+	}
+	if err := l.Check(); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+}
+
+func TestLedgerHolds(t *testing.T) {
+	l := newTestLedger(t, map[AccountID]int{"card": 100, "shop": 0})
+
+	h1, err := l.Authorize("card", 70)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if s := balanceOf(t, l, "card"); s.Balance != 100 || s.Available() != 30 {
+		t.Errorf("Expected 100 balance and 30 available, got %+v", s)
+	}
+	if _, err := l.Transfer("card", "shop", 31); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Expected held funds to be unspendable, got %v", err)
+	}
+	if _, err := l.Authorize("card", 31); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Expected a second hold over the available amount to fail, got %v", err)
+	}
+
+	// Capture less than authorized: the rest is released.
+	if err := l.Capture(h1, "shop", 50); err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	if s := balanceOf(t, l, "card"); s.Balance != 50 || s.Held != 0 {
+		t.Errorf("Expected 50 balance and nothing held, got %+v", s)
+	}
+	if err := l.Capture(h1, "shop", 1); !errors.Is(err, ErrHoldClosed) {
+		t.Errorf("Expected a second capture to fail, got %v", err)
+	}
+
+	h2, _ := l.Authorize("card", 20)
+	if err := l.Release(h2); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := l.Release(h2); !errors.Is(err, ErrHoldClosed) {
+		t.Errorf("Expected a second release to fail, got %v", err)
+	}
+	if err := l.Release(999); !errors.Is(err, ErrUnknownHold) {
+		t.Errorf("Expected ErrUnknownHold, got %v", err)
+	}
+
+	if s := balanceOf(t, l, "shop"); s.Balance != 50 {
+		t.Errorf("Expected shop to have 50, got %+v", s)
+	}
+	if err := l.Check(); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+}
+
+func TestLedgerReconstructFromJournal(t *testing.T) {
+	l := newTestLedger(t, map[AccountID]int{"a": 10, "b": 0})
+	l.Transfer("a", "b", 4)
+	h, _ := l.Authorize("b", 3)
+	l.Capture(h, "a", 2)
+
+	journal := l.Journal()
+	kinds := ""
+	for _, e := range journal {
+		kinds += e.Kind.String() + " "
+	}
+	if want := "transfer transfer hold capture release "; kinds != want {
+		t.Errorf("Expected journal %q, got %q", want, kinds)
+	}
+
+	final := Reconstruct(journal)
+	if final["a"] != (AccountState{Balance: 8}) || final["b"] != (AccountState{Balance: 2}) {
+		t.Errorf("Unexpected reconstruction %+v", final)
+	}
+
+	// Replaying a prefix gives historical balances: right after the first transfer.
+	past := Reconstruct(journal[:2])
+	if past["a"].Balance != 6 || past["b"].Balance != 4 {
+		t.Errorf("Expected a=6 b=4 after two entries, got %+v", past)
+	}
+
+	// The returned journal is a copy.
+	journal[0].Amount = 1_000_000
+	if err := l.Check(); err != nil {
+		t.Errorf("Expected mutating the returned journal to be harmless, got %v", err)
+	}
+}
+
+func TestLedgerCheckDetectsCorruption(t *testing.T) {
+	l := newTestLedger(t, map[AccountID]int{"a": 10})
+
+	// Bypass the ledger, as code holding a raw *Account could.
+	l.accounts["a"].Deposit(5)
+
+	err := l.Check()
+	if !errors.Is(err, ErrInvariantViolation) {
+		t.Fatalf("Expected ErrInvariantViolation, got %v", err)
+	}
+}
+
+func TestLedgerConcurrentTransfersDoNotDeadlock(t *testing.T) {
+	ids := []AccountID{"a", "b", "c", "d"}
+	balances := map[AccountID]int{}
+	for _, id := range ids {
+		balances[id] = 1000
+	}
+	l := newTestLedger(t, balances)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				// Opposite directions on the same pairs: A->B and B->A race constantly.
+				from, to := ids[(g+i)%len(ids)], ids[(g+i+1+g%2)%len(ids)]
+				if g%2 == 1 {
+					from, to = to, from
+				}
+				if from == to {
+					continue
+				}
+				switch i % 10 {
+				case 0:
+					if h, err := l.Authorize(from, 5); err == nil {
+						l.Capture(h, to, 3)
+					}
+				case 5:
+					if err := l.Check(); err != nil {
+						panic(fmt.Sprintf("invariant broken mid-run: %v", err))
+					}
+				default:
+					l.Transfer(from, to, 1+i%7)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := l.Check(); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	total := 0
+	for _, id := range ids {
+		total += balanceOf(t, l, id).Balance
+	}
+	if total != 4000 {
+		t.Errorf("Expected 4000 across accounts, got %d", total)
+	}
+}
@@ -0,0 +1,166 @@
+package pointers
+
+import (
+	"slices"
+	"testing"
+)
+
+type countingSink struct {
+	n       int
+	lastSeq uint64
+}
+
+func (s *countingSink) Consume(e Event) {
+	s.n++
+	s.lastSeq = e.Seq
+}
+
+type batchSink struct {
+	batches [][]uint64 // Seqs per batch, recorded only when record is set
+	record  bool
+	n       int
+}
+
+func (s *batchSink) Consume(e Event) { panic("batch sink must receive batches") }
+
+func (s *batchSink) ConsumeBatch(events []Event) {
+	s.n += len(events)
+	if s.record {
+		seqs := make([]uint64, len(events))
+		for i, e := range events {
+			seqs[i] = e.Seq
+		}
+		s.batches = append(s.batches, seqs)
+	}
+}
+
+func TestEventStreamDeliversInOrder(t *testing.T) {
+	var got []Event
+	stream := NewEventStream(3, EventSinkFunc(func(e Event) { got = append(got, e) }))
+
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		stream.Emit(id, "payload-"+id) // Capacity rounds up to 4: "e" forces a flush.
+	}
+	if len(got) != 4 || stream.Len() != 1 {
+		t.Fatalf("Expected 4 delivered and 1 pending, got %d and %d", len(got), stream.Len())
+	}
+	stream.Flush()
+
+	var ids []string
+	for i, e := range got {
+		ids = append(ids, e.ID)
+		if e.Seq != uint64(i+1) {
+			t.Errorf("Expected seq %d, got %d", i+1, e.Seq)
+		}
+	}
+	if !slices.Equal(ids, []string{"a", "b", "c", "d", "e"}) {
+		t.Errorf("Expected events in order, got %v", ids)
+	}
+}
+
+func TestEventStreamBatchesWrapAround(t *testing.T) {
+	sink := &batchSink{record: true}
+	stream := NewEventStream(4, sink)
+
+	for range 3 {
+		stream.Emit("x", "")
+	}
+	stream.Flush() // Seqs 1-3 from slots 0-2.
+	for range 3 {
+		stream.Emit("y", "")
+	}
+	stream.Flush() // Seqs 4-6 occupy slots 3, 0, 1: delivered as two segments.
+
+	want := [][]uint64{{1, 2, 3}, {4}, {5, 6}}
+	if !slices.EqualFunc(sink.batches, want, slices.Equal) {
+		t.Errorf("Expected batches %v, got %v", want, sink.batches)
+	}
+}
+
+func TestEventStreamSlotResetsReusedEvents(t *testing.T) {
+	var got []Event
+	stream := NewEventStream(1, EventSinkFunc(func(e Event) { got = append(got, e) }))
+
+	e := stream.Slot()
+	e.ID, e.Payload = "first", "secret"
+	stream.Commit()
+
+	e = stream.Slot() // Flushes "first" and reuses its slot.
+	e.ID = "second"
+	stream.Commit()
+	stream.Flush()
+
+	if got[1].Payload != "" {
+		t.Errorf("Expected a reused slot to start empty, got payload %q", got[1].Payload)
+	}
+}
+
+func TestEventStreamZeroAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation guard")
+	}
+
+	tests := []struct {
+		name string
+		sink EventSink
+	}{
+		{"value sink", &countingSink{}},
+		{"batch sink", &batchSink{}},
+		{"legacy pointer sink", &legacySink{}},
+	}
+	for _, tt := range tests {
+		stream := NewEventStream(64, tt.sink)
+		i := 0
+		allocs := testing.AllocsPerRun(10_000, func() {
+			e := stream.Slot()
+			e.ID = "evt"
+			e.Payload = "cpu=0.42"
+			stream.Commit()
+			if i++; i%64 == 0 {
+				stream.Flush()
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("%s: Expected 0 allocations per event, got %v", tt.name, allocs)
+		}
+	}
+}
+
+func TestProcessStreamZeroAllocsPerEvent(t *testing.T) {
+	// The ring and sink are allocated once per call; the 1000 events add nothing.
+	allocs := testing.AllocsPerRun(100, func() { ProcessStream() })
+	if allocs > 3 {
+		t.Errorf("Expected ProcessStream to allocate only its ring and sink, got %v allocations", allocs)
+	}
+}
+
+var benchEvent *Event
+
+func BenchmarkEventPerPointer(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		// The old pattern: a fresh heap event per iteration.
+		benchEvent = &Event{ID: "evt", Payload: "cpu=0.42", Seq: uint64(i)}
+	}
+}
+
+func BenchmarkEventStreamValueSink(b *testing.B) {
+	b.ReportAllocs()
+	stream := NewEventStream(1024, &countingSink{})
+	for b.Loop() {
+		stream.Emit("evt", "cpu=0.42")
+	}
+	stream.Flush()
+}
+
+func BenchmarkEventStreamBatchSink(b *testing.B) {
+	b.ReportAllocs()
+	stream := NewEventStream(1024, &batchSink{})
+	for b.Loop() {
+		e := stream.Slot()
+		e.ID = "evt"
+		e.Payload = "cpu=0.42"
+		stream.Commit()
+	}
+	stream.Flush()
+}
@@ -1,6 +1,7 @@
 package errorsfunc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -31,20 +32,29 @@ func publishEvent(data string) error {
 	return ErrQueueFull // simulating a failure
 }
 
+// Orchestrate runs the onboarding flow as a saga (see ex04_saga.go). Each step wraps
+// its failure with context using %w, so errors.Is still finds the root cause through
+// the *SagaError.
 func Orchestrate(userID string) error {
-	err := fetchUser(userID)
-	if err != nil {
-		// BUG: Returning raw error deletes context.
-		// TODO: Wrap it!
-		return err
-	}
-
-	err = publishEvent("user_created")
-	if err != nil {
-		// BUG: Creating a new error destroys the original type (`%v` instead of `%w`).
-		// TODO: Wrap it correctly using `%w`.
-		return fmt.Errorf("failed to publish event: %v", err)
-	}
-
-	return nil
+	saga := NewSaga("onboard-user", NewMemorySagaStore(),
+		SagaStep{
+			Name: "fetch-user",
+			Action: func(ctx context.Context, data SagaData) error {
+				if err := fetchUser(userID); err != nil {
+					return fmt.Errorf("failed to fetch user: %w", err)
+				}
+				return nil
+			},
+		},
+		SagaStep{
+			Name: "publish-event",
+			Action: func(ctx context.Context, data SagaData) error {
+				if err := publishEvent("user_created"); err != nil {
+					return fmt.Errorf("failed to publish event: %w", err)
+				}
+				return nil
+			},
+		},
+	)
+	return saga.Run(context.Background(), "onboard-"+userID, SagaData{"user_id": userID})
 }
@@ -0,0 +1,494 @@
+package errorsfunc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+)
+
+// Context: A Layered Configuration Loader
+// Every service re-implements the same thing: built-in defaults, overridden by a
+// config file, overridden by environment variables, overridden by command-line flags.
+// And every version of it gets the error paths wrong: a typo in a file key is
+// silently ignored, or `TIMEOUT=abc` surfaces as "strconv.Atoi: invalid syntax" with
+// no hint of which variable was wrong.
+//
+// Why this matters: Errors are values, so they can carry the context an operator
+// needs. Every failure is a `*ConfigError` naming the source (file, env or flag), the
+// exact origin (`app.conf:12`, `APP_DB_PORT`, `--db.port`) and the key. It still
+// unwraps to `ErrEmptyPayload`/`ErrInvalidFormat`/`ErrUnknownKey`, so callers branch
+// with `errors.Is`. The loader reports every problem it finds at once (`errors.Join`)
+// instead of making the operator fix them one restart at a time.
+//
+// Struct tags:
+//
+//	config:"name"         key (default: the field name in snake_case); nested structs
+//	                      use dotted keys, e.g. "db.port"
+//	config:"name,secret"  masked in Describe
+//	config:"-"            ignored
+//	default:"30s"         built-in default
+//	env:"NAME"            environment variable (default: Prefix + KEY with "." -> "_")
+//	flag:"name"           flag name (default: key with "_" -> "-")
+//
+// Supported field types: strings, bools, ints, uints, floats, time.Duration and any
+// type implementing encoding.TextUnmarshaler.
+
+var (
+	ErrUnknownKey   = errors.New("unknown key")
+	ErrNameConflict = errors.New("name used by two settings")
+)
+
+// Source is a configuration layer. Later sources override earlier ones.
+type Source int
+
+const (
+	SourceDefault Source = iota
+	SourceFile
+	SourceEnv
+	SourceFlag
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceFile:
+		return "file"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	default:
+		return fmt.Sprintf("Source(%d)", int(s))
+	}
+}
+
+// ConfigError describes one problem with one setting.
+type ConfigError struct {
+	Source Source
+	Origin string // File path and line, variable name or flag name
+	Key    string // Empty if the problem is not tied to a key
+	Err    error
+}
+
+func (e *ConfigError) Error() string {
+	msg := fmt.Sprintf("config %s %s", e.Source, e.Origin)
+	if e.Key != "" {
+		msg += fmt.Sprintf(": key %q", e.Key)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Setting is the effective value of one key and where it came from.
+type Setting struct {
+	Key    string
+	Value  string
+	Source Source
+	Origin string
+	Secret bool
+}
+
+// Settings lists every key of a loaded struct, in field order.
+type Settings []Setting
+
+// Describe renders the effective configuration as an aligned table. Secret values are
+// masked.
+func (s Settings) Describe() string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, st := range s {
+		value := strconv.Quote(st.Value)
+		if st.Secret && st.Value != "" {
+			value = `"****"`
+		}
+		origin := st.Source.String()
+		if st.Origin != "" {
+			origin += " " + st.Origin
+		}
+		fmt.Fprintf(tw, "%s\t= %s\t(%s)\n", st.Key, value, origin)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+// Loader merges configuration layers into a struct.
+type Loader struct {
+	File      string                          // Optional; ".json" files are JSON, anything else is key=value
+	EnvPrefix string                          // Prepended to derived environment variable names
+	Args      []string                        // Command-line arguments, without the program name
+	LookupEnv func(key string) (string, bool) // Defaults to os.LookupEnv
+}
+
+// configField is one settable leaf of the destination struct.
+type configField struct {
+	key, env, flag string
+	secret         bool
+	value          reflect.Value
+	setting        *Setting
+}
+
+// Load fills dst (a pointer to a struct) from defaults, file, environment and flags,
+// in that order, and returns where every value came from.
+func (l Loader) Load(dst any) (Settings, error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: destination must be a pointer to a struct, got %T", dst)
+	}
+
+	var fields []*configField
+	var defaults []error
+	collectFields(rv.Elem(), "", l.EnvPrefix, &fields, &defaults)
+	if err := checkNames(fields); err != nil {
+		return nil, err
+	}
+	settings := make(Settings, len(fields))
+	byKey := make(map[string]*configField, len(fields))
+	for i, f := range fields {
+		settings[i] = Setting{Key: f.key, Value: textOf(f.value), Secret: f.secret}
+		f.setting = &settings[i]
+		byKey[f.key] = f
+	}
+
+	errs := defaults
+	if l.File != "" {
+		errs = append(errs, l.loadFile(byKey)...)
+	}
+	errs = append(errs, l.loadEnv(fields)...)
+	errs = append(errs, l.loadFlags(fields)...)
+	return settings, errors.Join(errs...)
+}
+
+// checkNames rejects struct tags that give two settings the same key, variable or
+// flag, or a flag name flag.FlagSet would panic on. Such a struct can never load
+// correctly, so nothing is loaded.
+func checkNames(fields []*configField) error {
+	var errs []error
+	keys := map[string]string{}
+	envs := map[string]string{}
+	flags := map[string]string{}
+	for _, f := range fields {
+		if other, ok := keys[f.key]; ok {
+			errs = append(errs, &ConfigError{Source: SourceDefault, Origin: "tag", Key: f.key, Err: fmt.Errorf("%w: key also used by %q", ErrNameConflict, other)})
+		}
+		if other, ok := envs[f.env]; ok {
+			errs = append(errs, &ConfigError{Source: SourceEnv, Origin: f.env, Key: f.key, Err: fmt.Errorf("%w: also used by %q", ErrNameConflict, other)})
+		}
+		if other, ok := flags[f.flag]; ok {
+			errs = append(errs, &ConfigError{Source: SourceFlag, Origin: "--" + f.flag, Key: f.key, Err: fmt.Errorf("%w: also used by %q", ErrNameConflict, other)})
+		}
+		if f.flag == "" || strings.HasPrefix(f.flag, "-") || strings.Contains(f.flag, "=") {
+			errs = append(errs, &ConfigError{Source: SourceFlag, Origin: "tag", Key: f.key, Err: fmt.Errorf("%w: flag name %q", ErrInvalidFormat, f.flag)})
+		}
+		keys[f.key], envs[f.env], flags[f.flag] = f.key, f.key, f.key
+	}
+	return errors.Join(errs...)
+}
+
+// collectFields walks v and records every leaf field, applying `default` tags.
+func collectFields(v reflect.Value, prefix, envPrefix string, out *[]*configField, errs *[]error) {
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("config"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(sf.Name)
+		}
+		key := prefix + name
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct && !isText(fv) {
+			collectFields(fv, key+".", envPrefix, out, errs)
+			continue
+		}
+
+		f := &configField{key: key, value: fv, secret: opts == "secret"}
+		f.env = sf.Tag.Get("env")
+		if f.env == "" {
+			f.env = envPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+		}
+		f.flag = sf.Tag.Get("flag")
+		if f.flag == "" {
+			f.flag = strings.ReplaceAll(key, "_", "-")
+		}
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			if err := setText(fv, def); err != nil {
+				// A bad default is a programming error, but report it like the rest.
+				*errs = append(*errs, &ConfigError{Source: SourceDefault, Origin: "tag", Key: key, Err: err})
+			}
+		}
+		*out = append(*out, f)
+	}
+}
+
+func (f *configField) set(raw string, src Source, origin string) error {
+	if err := setText(f.value, raw); err != nil {
+		if f.secret {
+			// Parse errors quote the input; for a secret that would leak it into logs.
+			err = fmt.Errorf("%w: value is not a valid %s", ErrInvalidFormat, f.value.Type())
+		}
+		return &ConfigError{Source: src, Origin: origin, Key: f.key, Err: err}
+	}
+	*f.setting = Setting{Key: f.key, Value: textOf(f.value), Source: src, Origin: origin, Secret: f.secret}
+	return nil
+}
+
+func (l Loader) loadFile(byKey map[string]*configField) []error {
+	data, err := os.ReadFile(l.File)
+	if err != nil {
+		return []error{&ConfigError{Source: SourceFile, Origin: l.File, Err: err}}
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []error{&ConfigError{Source: SourceFile, Origin: l.File, Err: ErrEmptyPayload}}
+	}
+
+	type pair struct{ key, value, origin string }
+	var pairs []pair
+	if strings.EqualFold(filepath.Ext(l.File), ".json") {
+		flat := map[string]string{}
+		if err := flattenJSON(data, flat); err != nil {
+			return []error{&ConfigError{Source: SourceFile, Origin: l.File, Err: fmt.Errorf("%w: %v", ErrInvalidFormat, err)}}
+		}
+		for _, k := range slices.Sorted(maps.Keys(flat)) {
+			pairs = append(pairs, pair{k, flat[k], l.File})
+		}
+	} else {
+		var errs []error
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; sc.Scan(); line++ {
+			text := strings.TrimSpace(sc.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			origin := fmt.Sprintf("%s:%d", l.File, line)
+			key, value, ok := strings.Cut(text, "=")
+			if !ok {
+				errs = append(errs, &ConfigError{Source: SourceFile, Origin: origin, Err: fmt.Errorf("%w: expected key=value", ErrInvalidFormat)})
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if uq, err := strconv.Unquote(value); err == nil {
+				value = uq
+			}
+			pairs = append(pairs, pair{strings.TrimSpace(key), value, origin})
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+	}
+
+	var errs []error
+	for _, p := range pairs {
+		f, ok := byKey[p.key]
+		if !ok {
+			errs = append(errs, &ConfigError{Source: SourceFile, Origin: p.origin, Key: p.key, Err: ErrUnknownKey})
+			continue
+		}
+		if err := f.set(p.value, SourceFile, p.origin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// flattenJSON turns nested objects into dotted keys with string values.
+func flattenJSON(data []byte, out map[string]string) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var root map[string]any
+	if err := dec.Decode(&root); err != nil {
+		return err
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return errors.New("unexpected data after the top-level object")
+	}
+
+	var walk func(prefix string, m map[string]any) error
+	walk = func(prefix string, m map[string]any) error {
+		for k, v := range m {
+			key := prefix + k
+			switch v := v.(type) {
+			case map[string]any:
+				if err := walk(key+".", v); err != nil {
+					return err
+				}
+			case string:
+				out[key] = v
+			case json.Number:
+				out[key] = v.String()
+			case bool:
+				out[key] = strconv.FormatBool(v)
+			case nil:
+				// null leaves the lower layer in place.
+			default:
+				return fmt.Errorf("key %q: unsupported JSON value %T", key, v)
+			}
+		}
+		return nil
+	}
+	return walk("", root)
+}
+
+func (l Loader) loadEnv(fields []*configField) []error {
+	lookup := l.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	var errs []error
+	for _, f := range fields {
+		if raw, ok := lookup(f.env); ok {
+			if err := f.set(raw, SourceEnv, f.env); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// flagValue routes flag.FlagSet parsing into a configField.
+type flagValue struct {
+	f    *configField
+	errs *[]error
+}
+
+func (v flagValue) String() string { return "" }
+
+func (v flagValue) Set(raw string) error {
+	if err := v.f.set(raw, SourceFlag, "--"+v.f.flag); err != nil {
+		*v.errs = append(*v.errs, err)
+	}
+	return nil // Keep parsing so every bad flag is reported.
+}
+
+func (v flagValue) IsBoolFlag() bool {
+	return v.f.value.Kind() == reflect.Bool
+}
+
+func (l Loader) loadFlags(fields []*configField) []error {
+	var errs []error
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	for _, f := range fields {
+		fs.Var(flagValue{f: f, errs: &errs}, f.flag, f.key)
+	}
+
+	err := fs.Parse(l.Args)
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+		// -h is a request, not a configuration problem: let the caller print usage.
+		errs = append(errs, err)
+	case strings.HasPrefix(err.Error(), "flag provided but not defined"):
+		// flag only reports failures as text; this is its message for unknown flags.
+		errs = append(errs, &ConfigError{Source: SourceFlag, Origin: "command line", Err: fmt.Errorf("%w: %v", ErrUnknownKey, err)})
+	default:
+		// A missing value ("flag needs an argument") or bad syntax ("bad flag syntax").
+		errs = append(errs, &ConfigError{Source: SourceFlag, Origin: "command line", Err: fmt.Errorf("%w: %v", ErrInvalidFormat, err)})
+	}
+	return errs
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+func isText(v reflect.Value) bool {
+	return v.Addr().Type().Implements(textUnmarshalerType)
+}
+
+// setText parses raw into v according to v's type.
+func setText(v reflect.Value, raw string) error {
+	if isText(v) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+		}
+		return nil
+	}
+
+	var err error
+	switch {
+	case v.Type() == reflect.TypeFor[time.Duration]():
+		var d time.Duration
+		if d, err = time.ParseDuration(raw); err == nil {
+			v.SetInt(int64(d))
+		}
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(raw); err == nil {
+			v.SetBool(b)
+		}
+	case v.CanInt():
+		var n int64
+		if n, err = strconv.ParseInt(raw, 10, v.Type().Bits()); err == nil {
+			v.SetInt(n)
+		}
+	case v.CanUint():
+		var n uint64
+		if n, err = strconv.ParseUint(raw, 10, v.Type().Bits()); err == nil {
+			v.SetUint(n)
+		}
+	case v.CanFloat():
+		var n float64
+		if n, err = strconv.ParseFloat(raw, v.Type().Bits()); err == nil {
+			v.SetFloat(n)
+		}
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %q is not a valid %s", ErrInvalidFormat, raw, v.Type())
+	}
+	return nil
+}
+
+// textOf formats v for Describe.
+func textOf(v reflect.Value) string {
+	if m, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// snakeCase converts a Go field name such as "MaxIdleConns" or "HTTPPort" into
+// "max_idle_conns" or "http_port".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
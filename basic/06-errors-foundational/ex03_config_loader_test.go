@@ -0,0 +1,249 @@
+package errorsfunc
+
+import (
+	"errors"
+	"flag"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type dbConfig struct {
+	Host     string `default:"localhost"`
+	Port     int    `default:"5432"`
+	Password string `config:"password,secret"`
+}
+
+type serviceConfig struct {
+	Timeout      time.Duration `default:"30s"`
+	Retries      int           `default:"3"`
+	Debug        bool
+	MaxIdleConns int        `default:"10"`
+	ListenAddr   netip.Addr `config:"listen" default:"127.0.0.1"`
+	DB           dbConfig
+	Region       string `env:"AWS_REGION" flag:"region" default:"us-east-1"`
+	internal     int
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func envMap(m map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := m[k]
+		return v, ok
+	}
+}
+
+func TestLoaderPrecedence(t *testing.T) {
+	file := writeConfigFile(t, "svc.conf", `
+# Service settings
+timeout = 5s
+retries = 5
+db.host = "db.internal"
+max_idle_conns = 20
+`)
+	l := Loader{
+		File:      file,
+		EnvPrefix: "SVC_",
+		LookupEnv: envMap(map[string]string{"SVC_RETRIES": "7", "SVC_DB_PASSWORD": "hunter2", "AWS_REGION": "eu-west-1"}),
+		Args:      []string{"--retries=9", "--debug", "--db.port", "6432"},
+	}
+
+	var cfg serviceConfig
+	settings, err := l.Load(&cfg)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	want := serviceConfig{
+		Timeout:      5 * time.Second,
+		Retries:      9,
+		Debug:        true,
+		MaxIdleConns: 20,
+		ListenAddr:   netip.MustParseAddr("127.0.0.1"),
+		DB:           dbConfig{Host: "db.internal", Port: 6432, Password: "hunter2"},
+		Region:       "eu-west-1",
+	}
+	if cfg != want {
+		t.Errorf("Expected %+v, got %+v", want, cfg)
+	}
+
+	sources := map[string]Source{}
+	origins := map[string]string{}
+	for _, s := range settings {
+		sources[s.Key] = s.Source
+		origins[s.Key] = s.Origin
+	}
+	tests := []struct {
+		key    string
+		source Source
+		origin string
+	}{
+		{"timeout", SourceFile, file + ":3"},
+		{"retries", SourceFlag, "--retries"},
+		{"listen", SourceDefault, ""},
+		{"db.host", SourceFile, file + ":5"},
+		{"db.password", SourceEnv, "SVC_DB_PASSWORD"},
+		{"region", SourceEnv, "AWS_REGION"},
+	}
+	for _, tt := range tests {
+		if sources[tt.key] != tt.source || origins[tt.key] != tt.origin {
+			t.Errorf("%s: Expected %s %q, got %s %q", tt.key, tt.source, tt.origin, sources[tt.key], origins[tt.key])
+		}
+	}
+
+	desc := settings.Describe()
+	if strings.Contains(desc, "hunter2") || !strings.Contains(desc, `"****"`) {
+		t.Errorf("Expected Describe to mask secrets:\n%s", desc)
+	}
+	if !strings.Contains(desc, `"9"`) || !strings.Contains(desc, "(flag --retries)") {
+		t.Errorf("Expected Describe to show value and origin:\n%s", desc)
+	}
+}
+
+func TestLoaderJSONFile(t *testing.T) {
+	file := writeConfigFile(t, "svc.json", `{"retries": 4, "debug": true, "db": {"host": "json-db", "port": null}}`)
+
+	var cfg serviceConfig
+	if _, err := (Loader{File: file, LookupEnv: envMap(nil)}).Load(&cfg); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Retries != 4 || !cfg.Debug || cfg.DB.Host != "json-db" || cfg.DB.Port != 5432 {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+}
+
+func TestLoaderTypedErrors(t *testing.T) {
+	file := writeConfigFile(t, "svc.conf", "retries = many\ntimeuot = 5s\nnot a pair\n")
+	empty := writeConfigFile(t, "empty.conf", "  \n")
+	trailing := writeConfigFile(t, "trailing.json", `{"retries": 4} garbage`)
+
+	tests := []struct {
+		name    string
+		loader  Loader
+		wantErr error
+		source  Source
+		origin  string
+		key     string
+	}{
+		{"empty file", Loader{File: empty}, ErrEmptyPayload, SourceFile, empty, ""},
+		{"bad line", Loader{File: file}, ErrInvalidFormat, SourceFile, file + ":3", ""},
+		{"trailing JSON", Loader{File: trailing}, ErrInvalidFormat, SourceFile, trailing, ""},
+		{"bad env value", Loader{EnvPrefix: "SVC_", LookupEnv: envMap(map[string]string{"SVC_TIMEOUT": "soon"})}, ErrInvalidFormat, SourceEnv, "SVC_TIMEOUT", "timeout"},
+		{"bad flag value", Loader{Args: []string{"--listen=not-an-ip"}}, ErrInvalidFormat, SourceFlag, "--listen", "listen"},
+		{"unknown flag", Loader{Args: []string{"--verbose"}}, ErrUnknownKey, SourceFlag, "command line", ""},
+		{"missing flag value", Loader{Args: []string{"--retries"}}, ErrInvalidFormat, SourceFlag, "command line", ""},
+	}
+	for _, tt := range tests {
+		if tt.loader.LookupEnv == nil {
+			tt.loader.LookupEnv = envMap(nil)
+		}
+		var cfg serviceConfig
+		_, err := tt.loader.Load(&cfg)
+
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Expected %v, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		var ce *ConfigError
+		if !errors.As(err, &ce) {
+			t.Errorf("%s: Expected a *ConfigError, got %T", tt.name, err)
+			continue
+		}
+		if ce.Source != tt.source || ce.Origin != tt.origin || ce.Key != tt.key {
+			t.Errorf("%s: Expected %s %q key %q, got %s %q key %q", tt.name, tt.source, tt.origin, tt.key, ce.Source, ce.Origin, ce.Key)
+		}
+	}
+}
+
+func TestLoaderRedactsSecretParseErrors(t *testing.T) {
+	var cfg struct {
+		PIN int `config:"pin,secret"`
+	}
+	_, err := Loader{LookupEnv: envMap(map[string]string{"PIN": "hunter2"})}.Load(&cfg)
+	if !errors.Is(err, ErrInvalidFormat) {
+		t.Fatalf("Expected ErrInvalidFormat, got %v", err)
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("Expected the secret to be redacted, got %q", err)
+	}
+}
+
+func TestLoaderReportsEveryProblem(t *testing.T) {
+	file := writeConfigFile(t, "svc.conf", "retries = many\ntimeuot = 5s\n")
+
+	var cfg serviceConfig
+	_, err := Loader{File: file, LookupEnv: envMap(nil)}.Load(&cfg)
+	if !errors.Is(err, ErrInvalidFormat) || !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Expected both the bad value and the unknown key, got %v", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{`svc.conf:1: key "retries"`, `svc.conf:2: key "timeuot"`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in %q", want, msg)
+		}
+	}
+}
+
+func TestLoaderHelpFlag(t *testing.T) {
+	var cfg serviceConfig
+	_, err := Loader{Args: []string{"-h"}, LookupEnv: envMap(nil)}.Load(&cfg)
+	if !errors.Is(err, flag.ErrHelp) || errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Expected flag.ErrHelp unchanged, got %v", err)
+	}
+}
+
+func TestLoaderRejectsNameConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		dst    any
+		source Source
+		key    string
+	}{
+		{"same flag", &struct {
+			A string `flag:"name"`
+			B string `flag:"name"`
+		}{}, SourceFlag, "b"},
+		{"same env", &struct {
+			A string `env:"NAME"`
+			B string `env:"NAME"`
+		}{}, SourceEnv, "b"},
+		{"same key", &struct {
+			A string `config:"x" env:"A" flag:"a"`
+			B string `config:"x" env:"B" flag:"b"`
+		}{}, SourceDefault, "x"},
+	}
+	for _, tt := range tests {
+		_, err := Loader{LookupEnv: envMap(nil)}.Load(tt.dst)
+		var ce *ConfigError
+		if !errors.Is(err, ErrNameConflict) || !errors.As(err, &ce) || ce.Source != tt.source || ce.Key != tt.key {
+			t.Errorf("%s: Expected a %s ErrNameConflict for %q, got %v", tt.name, tt.source, tt.key, err)
+		}
+	}
+
+	bad := &struct {
+		A string `flag:"-a"`
+	}{}
+	if _, err := (Loader{LookupEnv: envMap(nil)}).Load(bad); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Expected an invalid flag name to be reported, got %v", err)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{"Timeout": "timeout", "MaxIdleConns": "max_idle_conns", "HTTPPort": "http_port", "DB": "db"}
+	for in, want := range tests {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q): Expected %q, got %q", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package errorsfunc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Context: Sagas: Undoing Work Across Services
+// `Orchestrate` fetches a user and publishes an event. Real onboarding spans four
+// services: create the account, provision storage, register billing, send the welcome
+// event. There is no distributed transaction, so when step 3 fails, steps 1 and 2 have
+// already happened and must be undone explicitly, or they are left as orphans.
+//
+// Why this matters: A saga pairs every step with a compensation and, on failure, runs
+// the compensations of the completed steps in reverse. The resulting error has to
+// answer three questions at once, so it is a structured type rather than a string:
+// what failed (`errors.Is(err, ErrQueueFull)` still works), what was rolled back, and
+// which rollbacks failed and need a human (`errors.Is(err, ErrCompensationFailed)`).
+//
+// Rules:
+// 1. State is saved to a `SagaStore` before and after every action and compensation,
+//    so `Resume` can pick up after a crash. A step that was running when the process
+//    died is run again, so actions and compensations must be idempotent.
+// 2. Compensations run even if ctx is cancelled: a cancelled request must not leave
+//    orphans behind.
+// 3. A failing compensation does not stop the others; the saga ends as
+//    `SagaCompensationFailed` and the error lists every failure.
+
+var (
+	ErrSagaNotFound       = errors.New("saga not found")
+	ErrSagaExists         = errors.New("saga already exists")
+	ErrSagaMismatch       = errors.New("saga record does not match definition")
+	ErrInvalidSagaID      = errors.New("invalid saga id")
+	ErrCompensationFailed = errors.New("compensation failed")
+)
+
+// SagaData is the saga's persisted scratch space. Actions record what later steps and
+// compensations need (e.g. the ID of a created account).
+type SagaData map[string]string
+
+// SagaStep is one unit of work and the action that undoes it.
+type SagaStep struct {
+	Name       string
+	Action     func(ctx context.Context, data SagaData) error
+	Compensate func(ctx context.Context, data SagaData) error // nil if there is nothing to undo
+}
+
+// SagaStatus is the overall state of a saga execution.
+type SagaStatus string
+
+const (
+	SagaRunning            SagaStatus = "running"
+	SagaCompensating       SagaStatus = "compensating"
+	SagaCompleted          SagaStatus = "completed"
+	SagaCompensated        SagaStatus = "compensated"
+	SagaCompensationFailed SagaStatus = "compensation_failed"
+)
+
+// StepStatus is the state of one step within an execution.
+type StepStatus string
+
+const (
+	StepPending            StepStatus = "pending"
+	StepRunning            StepStatus = "running"
+	StepSucceeded          StepStatus = "succeeded"
+	StepFailed             StepStatus = "failed"
+	StepCompensating       StepStatus = "compensating"
+	StepCompensated        StepStatus = "compensated"
+	StepCompensationFailed StepStatus = "compensation_failed"
+)
+
+// StepRecord is the persisted state of one step.
+type StepRecord struct {
+	Name   string     `json:"name"`
+	Status StepStatus `json:"status"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// SagaRecord is the persisted state of one saga execution.
+type SagaRecord struct {
+	ID     string       `json:"id"`
+	Saga   string       `json:"saga"`
+	Status SagaStatus   `json:"status"`
+	Steps  []StepRecord `json:"steps"`
+	Data   SagaData     `json:"data,omitempty"`
+}
+
+func (r SagaRecord) clone() SagaRecord {
+	r.Steps = slices.Clone(r.Steps)
+	r.Data = maps.Clone(r.Data)
+	return r
+}
+
+// SagaStore persists saga records.
+type SagaStore interface {
+	// Create saves the first record of an execution, or returns ErrSagaExists if
+	// rec.ID already has one. It is atomic, so two runners cannot both start an ID.
+	Create(ctx context.Context, rec SagaRecord) error
+	Save(ctx context.Context, rec SagaRecord) error
+	// Load returns ErrSagaNotFound if there is no record for id.
+	Load(ctx context.Context, id string) (SagaRecord, error)
+}
+
+// CompensationResult records one compensation that ran.
+type CompensationResult struct {
+	Step string
+	Err  error
+}
+
+// SagaError is returned when a step fails.
+type SagaError struct {
+	Saga          string
+	ID            string
+	Step          string // Step whose action failed
+	Err           error  // The action's error
+	Compensations []CompensationResult
+}
+
+func (e *SagaError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "saga %s/%s: step %q failed: %v", e.Saga, e.ID, e.Step, e.Err)
+	if ok := e.Compensated(); len(ok) > 0 {
+		fmt.Fprintf(&b, "; compensated %s", strings.Join(ok, ", "))
+	}
+	for _, c := range e.Compensations {
+		if c.Err != nil {
+			fmt.Fprintf(&b, "; compensating %q failed: %v", c.Step, c.Err)
+		}
+	}
+	return b.String()
+}
+
+// Unwrap exposes the step error, every compensation error and, if any compensation
+// failed, ErrCompensationFailed.
+func (e *SagaError) Unwrap() []error {
+	errs := []error{e.Err}
+	for _, c := range e.Compensations {
+		if c.Err != nil {
+			errs = append(errs, c.Err)
+		}
+	}
+	if len(errs) > 1 {
+		errs = append(errs, ErrCompensationFailed)
+	}
+	return errs
+}
+
+// Compensated returns the steps that were successfully undone, in the order they ran.
+func (e *SagaError) Compensated() []string {
+	var out []string
+	for _, c := range e.Compensations {
+		if c.Err == nil {
+			out = append(out, c.Step)
+		}
+	}
+	return out
+}
+
+// Saga is a reusable definition; every Run is an independent execution.
+type Saga struct {
+	name  string
+	steps []SagaStep
+	store SagaStore
+}
+
+// NewSaga defines a saga whose executions are persisted in store.
+func NewSaga(name string, store SagaStore, steps ...SagaStep) *Saga {
+	return &Saga{name: name, steps: slices.Clone(steps), store: store}
+}
+
+// execution carries the in-memory errors of one drive, which are richer than the
+// strings that survive persistence.
+type execution struct {
+	rec   SagaRecord
+	cause error
+	comps []CompensationResult
+}
+
+// Run starts a new execution identified by id.
+func (s *Saga) Run(ctx context.Context, id string, data SagaData) error {
+	rec := SagaRecord{ID: id, Saga: s.name, Status: SagaRunning, Data: maps.Clone(data)}
+	if rec.Data == nil {
+		rec.Data = SagaData{}
+	}
+	for _, st := range s.steps {
+		rec.Steps = append(rec.Steps, StepRecord{Name: st.Name, Status: StepPending})
+	}
+	if err := s.store.Create(ctx, rec.clone()); err != nil {
+		return fmt.Errorf("saga %s/%s: create: %w", s.name, id, err)
+	}
+	return s.drive(ctx, &execution{rec: rec})
+}
+
+// Resume continues the execution id from its last saved state. For a finished
+// execution it only reconstructs the outcome.
+func (s *Saga) Resume(ctx context.Context, id string) error {
+	rec, err := s.store.Load(ctx, id)
+	if err != nil {
+		return fmt.Errorf("saga %s/%s: load: %w", s.name, id, err)
+	}
+	if rec.Saga != s.name || len(rec.Steps) != len(s.steps) {
+		return fmt.Errorf("%w: %s/%s", ErrSagaMismatch, rec.Saga, id)
+	}
+	for i, st := range rec.Steps {
+		if st.Name != s.steps[i].Name {
+			return fmt.Errorf("%w: step %d is %q, definition has %q", ErrSagaMismatch, i, st.Name, s.steps[i].Name)
+		}
+	}
+	if rec.Data == nil {
+		rec.Data = SagaData{}
+	}
+	return s.drive(ctx, &execution{rec: rec})
+}
+
+func (s *Saga) save(ctx context.Context, ex *execution) error {
+	if err := s.store.Save(ctx, ex.rec.clone()); err != nil {
+		return fmt.Errorf("saga %s/%s: persist state: %w", s.name, ex.rec.ID, err)
+	}
+	return nil
+}
+
+// drive advances an execution until it completes or is fully compensated.
+func (s *Saga) drive(ctx context.Context, ex *execution) error {
+	// ctx only gates and is passed to actions. State is always saved with cctx: a
+	// store that honors cancellation would otherwise fail the save that records a
+	// cancelled step, and drive would return before compensating.
+	cctx := context.WithoutCancel(ctx)
+	rec := &ex.rec
+	for rec.Status == SagaRunning {
+		i := slices.IndexFunc(rec.Steps, func(st StepRecord) bool { return st.Status != StepSucceeded })
+		if i < 0 {
+			rec.Status = SagaCompleted
+			return s.save(cctx, ex)
+		}
+
+		rec.Steps[i].Status = StepRunning
+		if err := s.save(cctx, ex); err != nil {
+			return err
+		}
+		err := ctx.Err()
+		if err == nil {
+			err = s.steps[i].Action(ctx, rec.Data)
+		}
+		if err != nil {
+			rec.Steps[i].Status, rec.Steps[i].Error = StepFailed, err.Error()
+			rec.Status = SagaCompensating
+			ex.cause = err
+		} else {
+			rec.Steps[i].Status = StepSucceeded
+		}
+		if err := s.save(cctx, ex); err != nil {
+			return err
+		}
+	}
+
+	if rec.Status == SagaCompensating {
+		for i := len(rec.Steps) - 1; i >= 0; i-- {
+			// A step still marked compensating was interrupted by a crash: run it again.
+			if st := rec.Steps[i].Status; st != StepSucceeded && st != StepCompensating {
+				continue
+			}
+			rec.Steps[i].Status = StepCompensating
+			if err := s.save(cctx, ex); err != nil {
+				return err
+			}
+			var err error
+			if comp := s.steps[i].Compensate; comp != nil {
+				err = comp(cctx, rec.Data)
+			}
+			if err != nil {
+				rec.Steps[i].Status, rec.Steps[i].Error = StepCompensationFailed, err.Error()
+			} else {
+				rec.Steps[i].Status = StepCompensated
+			}
+			ex.comps = append(ex.comps, CompensationResult{Step: rec.Steps[i].Name, Err: err})
+			if err := s.save(cctx, ex); err != nil {
+				return err
+			}
+		}
+
+		rec.Status = SagaCompensated
+		if slices.ContainsFunc(rec.Steps, func(st StepRecord) bool { return st.Status == StepCompensationFailed }) {
+			rec.Status = SagaCompensationFailed
+		}
+		if err := s.save(cctx, ex); err != nil {
+			return err
+		}
+	}
+
+	return s.outcome(ex)
+}
+
+// outcome builds the error for a finished execution, preferring the live errors and
+// falling back to the persisted messages for work done before a crash.
+func (s *Saga) outcome(ex *execution) error {
+	rec := ex.rec
+	if rec.Status == SagaCompleted {
+		return nil
+	}
+
+	sagaErr := &SagaError{Saga: rec.Saga, ID: rec.ID, Err: ex.cause}
+	for _, st := range rec.Steps {
+		if st.Status == StepFailed {
+			sagaErr.Step = st.Name
+			if sagaErr.Err == nil {
+				sagaErr.Err = errors.New(st.Error)
+			}
+		}
+	}
+
+	live := make(map[string]error, len(ex.comps))
+	for _, c := range ex.comps {
+		live[c.Step] = c.Err
+	}
+	for _, st := range slices.Backward(rec.Steps) {
+		switch st.Status {
+		case StepCompensated:
+			sagaErr.Compensations = append(sagaErr.Compensations, CompensationResult{Step: st.Name})
+		case StepCompensationFailed:
+			err, ok := live[st.Name]
+			if !ok {
+				err = errors.New(st.Error)
+			}
+			sagaErr.Compensations = append(sagaErr.Compensations, CompensationResult{Step: st.Name, Err: err})
+		}
+	}
+	return sagaErr
+}
+
+// MemorySagaStore keeps records in memory. It survives nothing, which makes it the
+// right store for tests and for sagas that do not need to outlive the process.
+type MemorySagaStore struct {
+	mu      sync.Mutex
+	records map[string]SagaRecord
+}
+
+func NewMemorySagaStore() *MemorySagaStore {
+	return &MemorySagaStore{records: make(map[string]SagaRecord)}
+}
+
+func (m *MemorySagaStore) Create(_ context.Context, rec SagaRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.records[rec.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrSagaExists, rec.ID)
+	}
+	m.records[rec.ID] = rec.clone()
+	return nil
+}
+
+func (m *MemorySagaStore) Save(_ context.Context, rec SagaRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[rec.ID] = rec.clone()
+	return nil
+}
+
+func (m *MemorySagaStore) Load(_ context.Context, id string) (SagaRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.records[id]
+	if !ok {
+		return SagaRecord{}, fmt.Errorf("%w: %s", ErrSagaNotFound, id)
+	}
+	return rec.clone(), nil
+}
+
+// FileSagaStore keeps one JSON file per execution in Dir, named after the ID. IDs
+// must therefore be plain file names: no path separators, "." or "..". Writes go
+// to a temporary file that is renamed into place, so a crash never leaves a torn
+// record.
+type FileSagaStore struct {
+	Dir string
+}
+
+func (f FileSagaStore) path(id string) (string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`+"\x00") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSagaID, id)
+	}
+	return filepath.Join(f.Dir, id+".json"), nil
+}
+
+// Create links a fully written temporary file to the record's path. Unlike a
+// rename, a link fails if the path exists, which makes the check and the write
+// one atomic step.
+func (f FileSagaStore) Create(_ context.Context, rec SagaRecord) error {
+	path, err := f.path(rec.ID)
+	if err != nil {
+		return err
+	}
+	tmp, err := f.writeTemp(rec)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if err := os.Link(tmp, path); errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrSagaExists, rec.ID)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (f FileSagaStore) Save(_ context.Context, rec SagaRecord) error {
+	path, err := f.path(rec.ID)
+	if err != nil {
+		return err
+	}
+	tmp, err := f.writeTemp(rec)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp) // No-op once renamed.
+	return os.Rename(tmp, path)
+}
+
+// writeTemp writes rec to a synced temporary file in Dir and returns its name.
+func (f FileSagaStore) writeTemp(rec SagaRecord) (string, error) {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(f.Dir, ".saga-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func (f FileSagaStore) Load(_ context.Context, id string) (SagaRecord, error) {
+	path, err := f.path(id)
+	if err != nil {
+		return SagaRecord{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return SagaRecord{}, fmt.Errorf("%w: %s", ErrSagaNotFound, id)
+	}
+	if err != nil {
+		return SagaRecord{}, err
+	}
+	var rec SagaRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return SagaRecord{}, fmt.Errorf("%w: saga record %s: %v", ErrInvalidFormat, id, err)
+	}
+	return rec, nil
+}
@@ -0,0 +1,306 @@
+package errorsfunc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// onboarding records the side effects of a four-service onboarding saga.
+type onboarding struct {
+	mu      sync.Mutex
+	calls   []string
+	failAt  string           // Step whose action fails
+	failErr error            // Error returned by failAt
+	undoErr map[string]error // Compensation errors by step
+}
+
+func (o *onboarding) log(call string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.calls = append(o.calls, call)
+}
+
+func (o *onboarding) steps() []SagaStep {
+	var steps []SagaStep
+	for _, name := range []string{"create-account", "provision-storage", "register-billing", "send-welcome"} {
+		steps = append(steps, SagaStep{
+			Name: name,
+			Action: func(ctx context.Context, data SagaData) error {
+				o.log("do " + name)
+				if name == o.failAt {
+					return o.failErr
+				}
+				data[name] = "done"
+				return nil
+			},
+			Compensate: func(ctx context.Context, data SagaData) error {
+				if data[name] != "done" {
+					return errors.New("compensating a step that never ran")
+				}
+				o.log("undo " + name)
+				return o.undoErr[name]
+			},
+		})
+	}
+	return steps
+}
+
+func TestSagaCompletes(t *testing.T) {
+	o := &onboarding{}
+	store := NewMemorySagaStore()
+	saga := NewSaga("onboard", store, o.steps()...)
+
+	if err := saga.Run(context.Background(), "u1", nil); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	rec, _ := store.Load(context.Background(), "u1")
+	if rec.Status != SagaCompleted || len(o.calls) != 4 {
+		t.Errorf("Expected 4 actions and a completed saga, got %s after %v", rec.Status, o.calls)
+	}
+	if err := saga.Run(context.Background(), "u1", nil); !errors.Is(err, ErrSagaExists) {
+		t.Errorf("Expected ErrSagaExists for a reused ID, got %v", err)
+	}
+}
+
+func TestSagaCompensatesInReverse(t *testing.T) {
+	o := &onboarding{failAt: "register-billing", failErr: ErrQueueFull}
+	store := NewMemorySagaStore()
+	err := NewSaga("onboard", store, o.steps()...).Run(context.Background(), "u1", nil)
+
+	want := []string{"do create-account", "do provision-storage", "do register-billing", "undo provision-storage", "undo create-account"}
+	if !slices.Equal(o.calls, want) {
+		t.Errorf("Expected calls %v, got %v", want, o.calls)
+	}
+
+	var sagaErr *SagaError
+	if !errors.As(err, &sagaErr) {
+		t.Fatalf("Expected a *SagaError, got %T: %v", err, err)
+	}
+	if !errors.Is(err, ErrQueueFull) || errors.Is(err, ErrCompensationFailed) {
+		t.Errorf("Expected the step cause and no compensation failure, got %v", err)
+	}
+	if sagaErr.Step != "register-billing" || !slices.Equal(sagaErr.Compensated(), []string{"provision-storage", "create-account"}) {
+		t.Errorf("Unexpected saga error %+v", sagaErr)
+	}
+
+	rec, _ := store.Load(context.Background(), "u1")
+	if rec.Status != SagaCompensated {
+		t.Errorf("Expected status %s, got %s", SagaCompensated, rec.Status)
+	}
+}
+
+func TestSagaRecordsFailedCompensations(t *testing.T) {
+	o := &onboarding{
+		failAt:  "send-welcome",
+		failErr: ErrConnectionLost,
+		undoErr: map[string]error{"provision-storage": ErrQueueFull},
+	}
+	store := NewMemorySagaStore()
+	err := NewSaga("onboard", store, o.steps()...).Run(context.Background(), "u1", nil)
+
+	if !errors.Is(err, ErrConnectionLost) || !errors.Is(err, ErrQueueFull) || !errors.Is(err, ErrCompensationFailed) {
+		t.Fatalf("Expected the step error, the compensation error and ErrCompensationFailed, got %v", err)
+	}
+	// A failed compensation does not stop the remaining ones.
+	if !slices.Contains(o.calls, "undo create-account") {
+		t.Errorf("Expected create-account to be compensated anyway, got %v", o.calls)
+	}
+	if !strings.Contains(err.Error(), `compensating "provision-storage" failed`) {
+		t.Errorf("Expected the message to name the failed compensation: %v", err)
+	}
+
+	rec, _ := store.Load(context.Background(), "u1")
+	if rec.Status != SagaCompensationFailed {
+		t.Errorf("Expected status %s, got %s", SagaCompensationFailed, rec.Status)
+	}
+}
+
+func TestSagaCompensatesAfterCancellation(t *testing.T) {
+	o := &onboarding{}
+	steps := o.steps()
+	ctx, cancel := context.WithCancel(context.Background())
+	inner := steps[1].Action
+	steps[1].Action = func(c context.Context, data SagaData) error {
+		err := inner(c, data)
+		cancel() // The client goes away after storage is provisioned.
+		return err
+	}
+
+	err := NewSaga("onboard", NewMemorySagaStore(), steps...).Run(ctx, "u1", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	want := []string{"do create-account", "do provision-storage", "undo provision-storage", "undo create-account"}
+	if !slices.Equal(o.calls, want) {
+		t.Errorf("Expected calls %v, got %v", want, o.calls)
+	}
+}
+
+var errCrash = errors.New("process crashed")
+
+// crashingStore stops persisting (simulating a crash) once a record shows the step
+// named crashAt in status.
+type crashingStore struct {
+	SagaStore
+	crashAt string
+	status  StepStatus
+}
+
+func (c crashingStore) Save(ctx context.Context, rec SagaRecord) error {
+	for _, st := range rec.Steps {
+		if st.Name == c.crashAt && st.Status == c.status {
+			return errCrash
+		}
+	}
+	return c.SagaStore.Save(ctx, rec)
+}
+
+func TestSagaResumesFromFileStore(t *testing.T) {
+	store := FileSagaStore{Dir: t.TempDir()}
+
+	o := &onboarding{failAt: "send-welcome", failErr: ErrConnectionLost}
+	err := NewSaga("onboard", crashingStore{store, "register-billing", StepRunning}, o.steps()...).Run(context.Background(), "u1", SagaData{"plan": "pro"})
+	if !errors.Is(err, errCrash) {
+		t.Fatalf("Expected the simulated crash, got %v", err)
+	}
+
+	// A new process, with a fresh definition, picks up from disk.
+	o2 := &onboarding{failAt: "send-welcome", failErr: ErrConnectionLost}
+	err = NewSaga("onboard", store, o2.steps()...).Resume(context.Background(), "u1")
+
+	want := []string{"do register-billing", "do send-welcome", "undo register-billing", "undo provision-storage", "undo create-account"}
+	if !slices.Equal(o2.calls, want) {
+		t.Errorf("Expected resumed calls %v, got %v", want, o2.calls)
+	}
+	if !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("Expected the step cause after resuming, got %v", err)
+	}
+
+	rec, loadErr := store.Load(context.Background(), "u1")
+	if loadErr != nil || rec.Status != SagaCompensated || rec.Data["plan"] != "pro" {
+		t.Errorf("Expected a compensated record keeping its data, got %+v (%v)", rec, loadErr)
+	}
+
+	// Resuming a finished saga reports the persisted outcome without running anything.
+	o3 := &onboarding{}
+	err = NewSaga("onboard", store, o3.steps()...).Resume(context.Background(), "u1")
+	if len(o3.calls) != 0 || err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Errorf("Expected the stored outcome and no calls, got %v after %v", err, o3.calls)
+	}
+
+	if err := NewSaga("other", store, o3.steps()...).Resume(context.Background(), "u1"); !errors.Is(err, ErrSagaMismatch) {
+		t.Errorf("Expected ErrSagaMismatch, got %v", err)
+	}
+	if err := NewSaga("onboard", store).Resume(context.Background(), "nope"); !errors.Is(err, ErrSagaNotFound) {
+		t.Errorf("Expected ErrSagaNotFound, got %v", err)
+	}
+}
+
+func TestSagaResumesInterruptedCompensation(t *testing.T) {
+	store := FileSagaStore{Dir: t.TempDir()}
+
+	// The process dies after undoing register-billing but before recording it.
+	o := &onboarding{failAt: "send-welcome", failErr: ErrConnectionLost}
+	err := NewSaga("onboard", crashingStore{store, "register-billing", StepCompensated}, o.steps()...).Run(context.Background(), "u1", nil)
+	if !errors.Is(err, errCrash) {
+		t.Fatalf("Expected the simulated crash, got %v", err)
+	}
+	rec, _ := store.Load(context.Background(), "u1")
+	if rec.Steps[2].Status != StepCompensating {
+		t.Fatalf("Expected register-billing to be saved as compensating, got %+v", rec.Steps)
+	}
+
+	o2 := &onboarding{}
+	err = NewSaga("onboard", store, o2.steps()...).Resume(context.Background(), "u1")
+	want := []string{"undo register-billing", "undo provision-storage", "undo create-account"}
+	if !slices.Equal(o2.calls, want) {
+		t.Errorf("Expected resumed calls %v, got %v", want, o2.calls)
+	}
+	if err == nil || errors.Is(err, ErrCompensationFailed) {
+		t.Errorf("Expected a fully compensated saga, got %v", err)
+	}
+}
+
+// ctxStore refuses to persist once the caller's context is done, like a database
+// driver would.
+type ctxStore struct {
+	SagaStore
+}
+
+func (c ctxStore) Save(ctx context.Context, rec SagaRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.SagaStore.Save(ctx, rec)
+}
+
+func TestSagaCompensatesWithContextAwareStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	o := &onboarding{}
+	steps := o.steps()
+	steps[2].Action = func(ctx context.Context, data SagaData) error {
+		cancel() // The request is abandoned while billing is being registered.
+		return ctx.Err()
+	}
+	store := NewMemorySagaStore()
+	err := NewSaga("onboard", ctxStore{store}, steps...).Run(ctx, "u1", nil)
+
+	want := []string{"do create-account", "do provision-storage", "undo provision-storage", "undo create-account"}
+	if !slices.Equal(o.calls, want) {
+		t.Errorf("Expected compensation despite the cancelled context, got %v", o.calls)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancellation as the cause, got %v", err)
+	}
+	if rec, _ := store.Load(context.Background(), "u1"); rec.Status != SagaCompensated {
+		t.Errorf("Expected the compensated state to be persisted, got %q", rec.Status)
+	}
+}
+
+func TestFileSagaStoreIDs(t *testing.T) {
+	store := FileSagaStore{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	for _, id := range []string{"a/x", `b\x`, "..", ""} {
+		if err := store.Save(ctx, SagaRecord{ID: id}); !errors.Is(err, ErrInvalidSagaID) {
+			t.Errorf("Save(%q): Expected ErrInvalidSagaID, got %v", id, err)
+		}
+		if _, err := store.Load(ctx, id); !errors.Is(err, ErrInvalidSagaID) {
+			t.Errorf("Load(%q): Expected ErrInvalidSagaID, got %v", id, err)
+		}
+	}
+
+	// Concurrent runners race for the same ID; exactly one may start it.
+	var mu sync.Mutex
+	var started int
+	step := SagaStep{Name: "only", Action: func(ctx context.Context, data SagaData) error {
+		mu.Lock()
+		defer mu.Unlock()
+		started++
+		return nil
+	}}
+	saga := NewSaga("onboard", store, step)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Go(func() { errs[i] = saga.Run(ctx, "u1", nil) })
+	}
+	wg.Wait()
+
+	var exists int
+	for _, err := range errs {
+		if errors.Is(err, ErrSagaExists) {
+			exists++
+		}
+	}
+	if started != 1 || exists != len(errs)-1 {
+		t.Errorf("Expected one runner to start and %d to get ErrSagaExists, got %d and %d", len(errs)-1, started, exists)
+	}
+}
@@ -19,28 +19,34 @@ import "fmt"
 func ReleaseResources() []string {
 	var cleanupLog []string
 
-	addLog := func(msg string) {
-		cleanupLog = append(cleanupLog, msg)
+	// addLog builds the cleanup at registration time, so each message is fixed when
+	// the resource is acquired rather than when it is released.
+	addLog := func(msg string) func() error {
+		return func() error {
+			cleanupLog = append(cleanupLog, msg)
+			return nil
+		}
 	}
 
+	// A deferred cleanup would run after the return value is taken, leaving the log
+	// empty. The scope releases everything, in LIFO order, before returning.
+	scope := NewScope("release-resources", 0)
+
 	// 1. Acquire Lock
-	// TODO: Fix defer order!
-	defer addLog("Lock_Released")
+	scope.AddFunc("lock", addLog("Lock_Released"))
 
 	// 2. Acquire DB
-	defer addLog("DB_Closed")
+	scope.AddFunc("db", addLog("DB_Closed"))
 
 	// 3. Acquire File
 	filename := "temp_file.txt"
-	defer func() {
-		// BUG: This captures `filename` by reference, but filename is mutated below!
-		// It will log "wrong_file.txt_Closed".
-		// TODO: Fix the capture so it logs the correct filename at the time of deferring.
-		addLog(fmt.Sprintf("%s_Closed", filename))
-	}()
+	scope.AddFunc(filename, addLog(fmt.Sprintf("%s_Closed", filename)))
 
 	// Simulating other logic that changes the variable...
 	filename = "wrong_file.txt"
 
+	if err := scope.Close(); err != nil {
+		cleanupLog = append(cleanupLog, err.Error())
+	}
 	return cleanupLog
 }
@@ -18,15 +18,13 @@ package defers
 
 func RunWorkers(jobID int, errCh chan error) {
 	go func() {
-		// BUG: No panic recovery boundary!
-		// If ProcessJob panics, the entire program dies instantly.
-		// TODO: Add a defer/recover block here. If a panic happens, capture the value
-		// and send `fmt.Errorf("worker panicked: %v", recovered_val)` down the errCh.
-		// If no panic, send nil.
-
-		ProcessJob(jobID)
-
-		errCh <- nil
+		// Protect is the recover boundary: a panic in ProcessJob comes back as a
+		// *PanicError ("worker panicked: ...") carrying the stack, instead of killing
+		// the process. Long-running consumers get restarts on top via Supervisor.
+		errCh <- Protect(func() error {
+			ProcessJob(jobID)
+			return nil
+		})
 	}()
 }
 
@@ -0,0 +1,232 @@
+package defers
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Context: Scoped Resource Management
+// Request handlers acquire a lock, a DB handle and a couple of files, each followed
+// by its own `defer`. The chains are fragile: a deferred closure captures a variable
+// that changes later, a `Close` error is dropped, and a slow close blocks the handler.
+//
+// Why this matters: A `Scope` turns the defer chain into data. Resources register
+// with it as they are acquired, and one deferred `Close` (or `CloseInto`) releases
+// them all:
+// 1. Release is LIFO, in the reverse order of registration.
+// 2. It runs even when the handler panics, because it is deferred.
+// 3. A cleanup that fails, panics or times out does not stop the others. Every
+//    failure is reported through `errors.Join`.
+// 4. Child scopes give a sub-task its own cleanup. The child is released on its own
+//    `Close`, or by its parent at the child's position in the LIFO order.
+// 5. With `SetScopeTracking(true)`, scopes that were created but never closed show
+//    up in `OpenScopes`. Tests use it as a leak report.
+
+var (
+	ErrScopeClosed    = errors.New("scope already closed")
+	ErrCleanupTimeout = errors.New("cleanup timed out")
+	ErrCleanupPanic   = errors.New("cleanup panicked")
+)
+
+// cleanup is one registered release action; child is set for child scopes.
+type cleanup struct {
+	name  string
+	fn    func() error
+	child *Scope
+}
+
+// Scope releases registered resources in LIFO order. It is safe for concurrent use.
+type Scope struct {
+	name    string
+	timeout time.Duration
+	parent  *Scope
+	site    string // Set only while tracking
+
+	mu       sync.Mutex
+	cleanups []cleanup
+	closed   bool
+}
+
+// NewScope creates a scope. A positive timeout bounds each cleanup: a cleanup still
+// running after it is reported as ErrCleanupTimeout and left behind, so a hung close
+// cannot block the rest.
+func NewScope(name string, timeout time.Duration) *Scope {
+	return newScope(name, timeout, nil)
+}
+
+func newScope(name string, timeout time.Duration, parent *Scope) *Scope {
+	s := &Scope{name: name, timeout: timeout, parent: parent}
+	if trackScopes.Load() {
+		s.site = "unknown"
+		if _, file, line, ok := runtime.Caller(2); ok {
+			s.site = fmt.Sprintf("%s:%d", file, line)
+		}
+		openScopes.Lock()
+		openScopes.m[s] = struct{}{}
+		openScopes.Unlock()
+	}
+	return s
+}
+
+// Name returns the scope's name; child names are prefixed with their parent's.
+func (s *Scope) Name() string { return s.name }
+
+// Add registers c to be closed with the scope. If the scope is already closed, c is
+// closed immediately and the result is joined with ErrScopeClosed.
+func (s *Scope) Add(c io.Closer) error {
+	return s.AddFunc(fmt.Sprintf("%T", c), c.Close)
+}
+
+// AddFunc registers a named cleanup function. The name appears in its errors.
+func (s *Scope) AddFunc(name string, fn func() error) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errors.Join(fmt.Errorf("%s: add %s: %w", s.name, name, ErrScopeClosed), s.run(cleanup{name: name, fn: fn}))
+	}
+	s.cleanups = append(s.cleanups, cleanup{name: name, fn: fn})
+	s.mu.Unlock()
+	return nil
+}
+
+// Child creates a scope released by s unless it is closed first. It inherits the
+// cleanup timeout. Creating a child of a closed scope returns an already closed one.
+func (s *Scope) Child(name string) *Scope {
+	c := newScope(s.name+"/"+name, s.timeout, s)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		c.Close()
+		return c
+	}
+	s.cleanups = append(s.cleanups, cleanup{name: name, child: c})
+	s.mu.Unlock()
+	return c
+}
+
+// Close releases every registered resource in LIFO order and returns all cleanup
+// errors joined. Closing an already closed scope returns nil.
+func (s *Scope) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	cleanups := s.cleanups
+	s.cleanups = nil
+	s.mu.Unlock()
+
+	if s.site != "" {
+		openScopes.Lock()
+		delete(openScopes.m, s)
+		openScopes.Unlock()
+	}
+
+	if p := s.parent; p != nil {
+		p.mu.Lock()
+		p.cleanups = slices.DeleteFunc(p.cleanups, func(c cleanup) bool { return c.child == s })
+		p.mu.Unlock()
+	}
+
+	var errs []error
+	for _, c := range slices.Backward(cleanups) {
+		if c.child != nil {
+			errs = append(errs, c.child.Close())
+			continue
+		}
+		errs = append(errs, s.run(c))
+	}
+	return errors.Join(errs...)
+}
+
+// CloseInto closes the scope and joins its errors into *errp. It is meant to be
+// deferred by a function with a named error result:
+//
+//	func handle() (err error) {
+//		scope := NewScope("handle", time.Second)
+//		defer scope.CloseInto(&err)
+func (s *Scope) CloseInto(errp *error) {
+	if err := s.Close(); err != nil {
+		*errp = errors.Join(*errp, err)
+	}
+}
+
+// run executes one cleanup, converting a panic into ErrCleanupPanic and enforcing
+// the scope's timeout.
+func (s *Scope) run(c cleanup) error {
+	call := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%w: %v", ErrCleanupPanic, r)
+			}
+		}()
+		return c.fn()
+	}
+
+	var err error
+	if s.timeout <= 0 {
+		err = call()
+	} else {
+		done := make(chan error, 1) // Buffered: an abandoned cleanup must not block forever.
+		go func() { done <- call() }()
+
+		timer := time.NewTimer(s.timeout)
+		defer timer.Stop()
+		select {
+		case err = <-done:
+		case <-timer.C:
+			err = fmt.Errorf("%w after %v", ErrCleanupTimeout, s.timeout)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: close %s: %w", s.name, c.name, err)
+	}
+	return nil
+}
+
+var trackScopes atomic.Bool
+
+// SetScopeTracking turns leak tracking on or off for scopes created afterwards.
+// Tracking costs a stack lookup per scope and keeps unclosed scopes reachable, so it
+// is meant for debugging and tests, not steady-state production.
+func SetScopeTracking(on bool) {
+	trackScopes.Store(on)
+}
+
+var openScopes = struct {
+	sync.Mutex
+	m map[*Scope]struct{}
+}{m: map[*Scope]struct{}{}}
+
+// ScopeLeak describes a scope that has not been closed.
+type ScopeLeak struct {
+	Name string
+	Site string // file:line where the scope was created
+}
+
+// OpenScopes reports every scope created while tracking was on and not yet closed,
+// sorted by name. In a test, check it after the code under test returns: anything
+// still open has leaked.
+func OpenScopes() []ScopeLeak {
+	openScopes.Lock()
+	defer openScopes.Unlock()
+
+	leaks := make([]ScopeLeak, 0, len(openScopes.m))
+	for s := range openScopes.m {
+		leaks = append(leaks, ScopeLeak{Name: s.name, Site: s.site})
+	}
+	slices.SortFunc(leaks, func(a, b ScopeLeak) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.Site, b.Site))
+	})
+	return leaks
+}
@@ -0,0 +1,204 @@
+package defers
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkNoOpenScopes turns on tracking and fails the test if it leaves any scope
+// unclosed.
+func checkNoOpenScopes(t *testing.T) {
+	t.Helper()
+	SetScopeTracking(true)
+	before := len(OpenScopes())
+	t.Cleanup(func() {
+		SetScopeTracking(false)
+		if leaks := OpenScopes(); len(leaks) > before {
+			t.Errorf("Leaked scopes: %+v", leaks)
+		}
+	})
+}
+
+type fakeFile struct {
+	name string
+	log  *[]string
+	err  error
+}
+
+func (f *fakeFile) Close() error {
+	*f.log = append(*f.log, "close "+f.name)
+	return f.err
+}
+
+func TestScopeReleasesLIFOAndJoinsErrors(t *testing.T) {
+	checkNoOpenScopes(t)
+
+	var log []string
+	errDisk := errors.New("disk full")
+	errLock := errors.New("lock lost")
+
+	scope := NewScope("handler", 0)
+	scope.AddFunc("lock", func() error { log = append(log, "unlock"); return errLock })
+	scope.Add(&fakeFile{name: "a.txt", log: &log, err: errDisk})
+	scope.AddFunc("cache", func() error { panic("nil map") })
+	scope.Add(&fakeFile{name: "b.txt", log: &log})
+
+	err := scope.Close()
+
+	want := []string{"close b.txt", "close a.txt", "unlock"}
+	if !slices.Equal(log, want) {
+		t.Errorf("Expected %v, got %v", want, log)
+	}
+	for _, target := range []error{errDisk, errLock, ErrCleanupPanic} {
+		if !errors.Is(err, target) {
+			t.Errorf("Expected %v in %v", target, err)
+		}
+	}
+	if !strings.Contains(err.Error(), "handler: close *defers.fakeFile: disk full") {
+		t.Errorf("Expected errors to name the scope and resource, got %v", err)
+	}
+
+	if err := scope.Close(); err != nil {
+		t.Errorf("Expected a second Close to return nil, got %v", err)
+	}
+	var late []string
+	if err := scope.Add(&fakeFile{name: "late", log: &late}); !errors.Is(err, ErrScopeClosed) || len(late) != 1 {
+		t.Errorf("Expected a late resource to be closed immediately with ErrScopeClosed, got %v", err)
+	}
+}
+
+func TestScopeReleasesOnPanic(t *testing.T) {
+	checkNoOpenScopes(t)
+
+	var log []string
+	handler := func() (err error) {
+		scope := NewScope("handler", 0)
+		defer scope.CloseInto(&err)
+
+		scope.AddFunc("db", func() error { log = append(log, "db"); return nil })
+		scope.AddFunc("file", func() error { log = append(log, "file"); return nil })
+		panic("handler bug")
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "handler bug" {
+				t.Errorf("Expected the panic to propagate, got %v", r)
+			}
+		}()
+		handler()
+	}()
+
+	if !slices.Equal(log, []string{"file", "db"}) {
+		t.Errorf("Expected LIFO release during the panic, got %v", log)
+	}
+}
+
+func TestScopeCloseIntoKeepsResultError(t *testing.T) {
+	errQuery := errors.New("query failed")
+	errClose := errors.New("close failed")
+
+	err := func() (err error) {
+		scope := NewScope("handler", 0)
+		defer scope.CloseInto(&err)
+		scope.AddFunc("conn", func() error { return errClose })
+		return errQuery
+	}()
+
+	if !errors.Is(err, errQuery) || !errors.Is(err, errClose) {
+		t.Errorf("Expected both the result and the cleanup error, got %v", err)
+	}
+}
+
+func TestScopeChildren(t *testing.T) {
+	checkNoOpenScopes(t)
+
+	var log []string
+	record := func(msg string) func() error {
+		return func() error { log = append(log, msg); return nil }
+	}
+
+	parent := NewScope("request", 0)
+	parent.AddFunc("conn", record("conn"))
+	early := parent.Child("upload")
+	early.AddFunc("tmp", record("upload tmp"))
+	child := parent.Child("render")
+	child.AddFunc("template", record("render template"))
+	child.AddFunc("buffer", record("render buffer"))
+	parent.AddFunc("span", record("span"))
+
+	early.Close() // Done with the upload: released now, and not again by the parent.
+	if err := parent.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	want := []string{"upload tmp", "span", "render buffer", "render template", "conn"}
+	if !slices.Equal(log, want) {
+		t.Errorf("Expected %v, got %v", want, log)
+	}
+	if child.Name() != "request/render" {
+		t.Errorf("Expected child name %q, got %q", "request/render", child.Name())
+	}
+	if err := parent.Child("late").AddFunc("x", record("late")); !errors.Is(err, ErrScopeClosed) {
+		t.Errorf("Expected a child of a closed scope to be closed, got %v", err)
+	}
+}
+
+func TestScopeTimeoutSkipsSlowCleanup(t *testing.T) {
+	checkNoOpenScopes(t)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	var log []string
+	scope := NewScope("handler", 20*time.Millisecond)
+	scope.AddFunc("fast", func() error { log = append(log, "fast"); return nil })
+	scope.AddFunc("nfs", func() error { <-release; return nil })
+
+	start := time.Now()
+	err := scope.Close()
+
+	if !errors.Is(err, ErrCleanupTimeout) || !strings.Contains(err.Error(), "close nfs") {
+		t.Errorf("Expected a timeout for the slow cleanup, got %v", err)
+	}
+	if !slices.Equal(log, []string{"fast"}) {
+		t.Errorf("Expected the remaining cleanups to run, got %v", log)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Close to give up on the slow cleanup, took %v", elapsed)
+	}
+}
+
+func TestOpenScopesReportsLeaks(t *testing.T) {
+	untracked := NewScope("untracked", 0)
+	defer untracked.Close()
+
+	SetScopeTracking(true)
+	defer SetScopeTracking(false)
+	leaked := NewScope("forgotten", 0)
+	child := leaked.Child("worker")
+
+	var names []string
+	for _, l := range OpenScopes() {
+		names = append(names, l.Name)
+		if l.Name == "forgotten" && !strings.Contains(l.Site, "ex03_scope_test.go:") {
+			t.Errorf("Expected the creation site, got %q", l.Site)
+		}
+	}
+	if !slices.Contains(names, "forgotten") || !slices.Contains(names, "forgotten/worker") {
+		t.Errorf("Expected both open scopes to be reported, got %v", names)
+	}
+	if slices.Contains(names, "untracked") {
+		t.Errorf("Expected a scope created with tracking off not to be recorded")
+	}
+
+	leaked.Close()
+	for _, l := range OpenScopes() {
+		if l.Name == leaked.Name() || l.Name == child.Name() {
+			t.Errorf("Expected closed scopes to leave the report, found %+v", l)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package defers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// Context: Supervised Workers
+// The service runs dozens of background consumers. Adding a recover boundary to
+// each goroutine stops one bad message from taking down the pod, but then what?
+// The consumer is gone, and nobody restarts it.
+//
+// Why this matters: Erlang answers this with supervisors. A worker that crashes is
+// restarted by its supervisor according to a strategy, and a worker that keeps
+// crashing escalates the failure instead of spinning forever.
+//
+// Design:
+// 1. `Protect` is the recover boundary. A panic becomes a `*PanicError` carrying
+//    the panic value and the stack trace of the panicking goroutine.
+// 2. `OneForOne` restarts only the crashed worker. `OneForAll` stops and restarts
+//    all of them, for workers that share state and must start together.
+// 3. More than `MaxRestarts` restarts within `Window` means the fault is not
+//    transient. `Run` stops every worker and returns `ErrTooManyRestarts`.
+// 4. Restarts wait with exponential backoff, from `MinBackoff` to `MaxBackoff`.
+// 5. A worker returning nil has finished and is not restarted. Returning an error
+//    counts as a crash.
+
+var ErrTooManyRestarts = errors.New("too many restarts")
+
+// PanicError is a recovered panic.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("worker panicked: %v", e.Value) }
+
+// Unwrap exposes the panic value when it is an error, e.g. a runtime.Error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Protect calls fn and converts a panic into a *PanicError.
+func Protect(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// Strategy decides which workers restart when one crashes.
+type Strategy int
+
+const (
+	OneForOne Strategy = iota
+	OneForAll
+)
+
+// Worker is a long-running task under supervision. Run must return when its
+// context is cancelled.
+type Worker struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// EventKind classifies supervisor events.
+type EventKind int
+
+const (
+	WorkerStarted EventKind = iota
+	WorkerCrashed
+	WorkerRestarted
+	WorkerFinished
+	SupervisorGaveUp
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case WorkerStarted:
+		return "started"
+	case WorkerCrashed:
+		return "crashed"
+	case WorkerRestarted:
+		return "restarted"
+	case WorkerFinished:
+		return "finished"
+	case SupervisorGaveUp:
+		return "gave up"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// SupervisorEvent reports a change in a worker's life cycle.
+type SupervisorEvent struct {
+	Kind   EventKind
+	Worker string
+	Err    error         // The crash, for WorkerCrashed and SupervisorGaveUp
+	Delay  time.Duration // The backoff before a restart, for WorkerRestarted
+	Time   time.Time
+}
+
+// SupervisorOptions configure a Supervisor. Zero values select the defaults.
+type SupervisorOptions struct {
+	Strategy    Strategy
+	MaxRestarts int           // Default 3
+	Window      time.Duration // Default 5s
+	MinBackoff  time.Duration // Default 100ms
+	MaxBackoff  time.Duration // Default 5s
+
+	// Events, if set, receives every event. Sends never block: events are dropped
+	// when the channel is full, so a slow reader cannot stall supervision.
+	Events chan<- SupervisorEvent
+}
+
+// Supervisor runs workers and restarts them when they crash.
+type Supervisor struct {
+	opts    SupervisorOptions
+	workers []Worker
+}
+
+// NewSupervisor creates a supervisor for the given workers.
+func NewSupervisor(opts SupervisorOptions, workers ...Worker) *Supervisor {
+	if opts.MaxRestarts <= 0 {
+		opts.MaxRestarts = 3
+	}
+	if opts.Window <= 0 {
+		opts.Window = 5 * time.Second
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(5*time.Second, opts.MinBackoff)
+	}
+	return &Supervisor{opts: opts, workers: workers}
+}
+
+type workerExit struct {
+	idx int
+	gen int
+	err error
+}
+
+type workerState struct {
+	gen     int
+	cancel  context.CancelFunc
+	running bool
+}
+
+// Run supervises the workers until they have all finished (returning nil), ctx is
+// cancelled (returning nil once every worker has stopped), or the restart limit is
+// exceeded (returning ErrTooManyRestarts wrapped with the last crash).
+func (s *Supervisor) Run(ctx context.Context) error {
+	ctx, cancelAll := context.WithCancel(ctx)
+	defer cancelAll()
+
+	exits := make(chan workerExit)
+	states := make([]workerState, len(s.workers))
+	active := 0 // Goroutines started and not yet collected, including stale ones.
+
+	start := func(i int, delay time.Duration) {
+		st := &states[i]
+		st.gen++
+		var wctx context.Context
+		wctx, st.cancel = context.WithCancel(ctx)
+		st.running = true
+		active++
+
+		w, gen := s.workers[i], st.gen
+		go func() {
+			if delay > 0 {
+				t := time.NewTimer(delay)
+				select {
+				case <-t.C:
+				case <-wctx.Done():
+					t.Stop()
+					exits <- workerExit{i, gen, wctx.Err()}
+					return
+				}
+			}
+			s.emit(SupervisorEvent{Kind: WorkerStarted, Worker: w.Name})
+			err := Protect(func() error { return w.Run(wctx) })
+			exits <- workerExit{i, gen, err}
+		}()
+	}
+	// stopAll cancels every worker and waits for all goroutines to return.
+	stopAll := func() {
+		for i := range states {
+			if states[i].running {
+				states[i].cancel()
+				states[i].running = false
+			}
+		}
+		for ; active > 0; active-- {
+			<-exits
+		}
+	}
+	runningCount := func() int {
+		n := 0
+		for _, st := range states {
+			if st.running {
+				n++
+			}
+		}
+		return n
+	}
+
+	for i := range s.workers {
+		start(i, 0)
+	}
+
+	var restarts []time.Time
+	for runningCount() > 0 {
+		var ex workerExit
+		select {
+		case <-ctx.Done():
+			stopAll()
+			return nil
+		case ex = <-exits:
+			active--
+		}
+
+		st := &states[ex.idx]
+		if ex.gen != st.gen || !st.running {
+			continue // A worker stopped by a restart; its exit was expected.
+		}
+		st.cancel()
+		st.running = false
+		name := s.workers[ex.idx].Name
+
+		if ex.err == nil {
+			s.emit(SupervisorEvent{Kind: WorkerFinished, Worker: name})
+			continue
+		}
+		if ctx.Err() != nil {
+			continue // Shutting down: the loop will observe ctx.Done.
+		}
+		s.emit(SupervisorEvent{Kind: WorkerCrashed, Worker: name, Err: ex.err})
+
+		now := time.Now()
+		cutoff := now.Add(-s.opts.Window)
+		for len(restarts) > 0 && !restarts[0].After(cutoff) {
+			restarts = restarts[1:]
+		}
+		restarts = append(restarts, now)
+		if len(restarts) > s.opts.MaxRestarts {
+			err := fmt.Errorf("%w: %d within %v, last crash in %q: %w", ErrTooManyRestarts, len(restarts), s.opts.Window, name, ex.err)
+			s.emit(SupervisorEvent{Kind: SupervisorGaveUp, Worker: name, Err: err})
+			stopAll()
+			return err
+		}
+		delay := s.backoff(len(restarts))
+
+		restart := []int{ex.idx}
+		if s.opts.Strategy == OneForAll {
+			restart = restart[:0]
+			for i := range states {
+				if states[i].running || i == ex.idx {
+					restart = append(restart, i)
+				}
+			}
+			stopAll()
+		}
+		for _, i := range restart {
+			s.emit(SupervisorEvent{Kind: WorkerRestarted, Worker: s.workers[i].Name, Delay: delay})
+			start(i, delay)
+		}
+	}
+	return nil
+}
+
+// backoff doubles the delay with each recent restart, capped at MaxBackoff.
+func (s *Supervisor) backoff(recent int) time.Duration {
+	d := s.opts.MinBackoff
+	for i := 1; i < recent && d < s.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, s.opts.MaxBackoff)
+}
+
+func (s *Supervisor) emit(e SupervisorEvent) {
+	if s.opts.Events == nil {
+		return
+	}
+	e.Time = time.Now()
+	select {
+	case s.opts.Events <- e:
+	default:
+	}
+}
@@ -0,0 +1,219 @@
+package defers
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProtectCapturesPanicWithStack(t *testing.T) {
+	err := Protect(func() error {
+		var m map[string]int
+		m["x"]++ // Assignment to a nil map.
+		return nil
+	})
+
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected a *PanicError, got %T: %v", err, err)
+	}
+	var rtErr runtime.Error
+	if !errors.As(err, &rtErr) {
+		t.Errorf("Expected the runtime error to be unwrappable, got %v", pe.Value)
+	}
+	if !strings.Contains(string(pe.Stack), "TestProtectCapturesPanicWithStack") {
+		t.Errorf("Expected the stack of the panicking goroutine, got:\n%s", pe.Stack)
+	}
+	if err := Protect(func() error { return nil }); err != nil {
+		t.Errorf("Expected nil without a panic, got %v", err)
+	}
+}
+
+func fastOptions(strategy Strategy, events chan SupervisorEvent) SupervisorOptions {
+	return SupervisorOptions{
+		Strategy:    strategy,
+		MaxRestarts: 3,
+		Window:      time.Minute,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  4 * time.Millisecond,
+		Events:      events,
+	}
+}
+
+// blockUntilDone is a well-behaved consumer: it runs until cancelled.
+func blockUntilDone(starts *atomic.Int32) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		starts.Add(1)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+}
+
+func TestSupervisorOneForOneRestartsOnlyTheCrashedWorker(t *testing.T) {
+	var steadyStarts, flakyStarts atomic.Int32
+	flaky := func(ctx context.Context) error {
+		if flakyStarts.Add(1) <= 2 {
+			panic("bad message")
+		}
+		<-ctx.Done()
+		return nil
+	}
+
+	events := make(chan SupervisorEvent, 64)
+	ctx, cancel := context.WithCancel(context.Background())
+	sup := NewSupervisor(fastOptions(OneForOne, events),
+		Worker{Name: "steady", Run: blockUntilDone(&steadyStarts)},
+		Worker{Name: "flaky", Run: flaky},
+	)
+
+	done := make(chan error, 1)
+	go func() { done <- sup.Run(ctx) }()
+
+	restarts := 0
+	for restarts < 2 {
+		e := <-events
+		if e.Kind == WorkerRestarted {
+			restarts++
+			if e.Worker != "flaky" {
+				t.Errorf("Expected only flaky to restart, got %q", e.Worker)
+			}
+		}
+		if e.Kind == WorkerCrashed {
+			var pe *PanicError
+			if !errors.As(e.Err, &pe) || len(pe.Stack) == 0 {
+				t.Errorf("Expected the crash to carry a panic stack, got %v", e.Err)
+			}
+		}
+	}
+	for flakyStarts.Load() < 3 {
+		runtime.Gosched()
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+	if steadyStarts.Load() != 1 {
+		t.Errorf("Expected steady to start once, got %d", steadyStarts.Load())
+	}
+}
+
+func TestSupervisorOneForAllRestartsEveryWorker(t *testing.T) {
+	var aStarts, bStarts atomic.Int32
+	errDecode := errors.New("decode failed")
+	failOnce := func(ctx context.Context) error {
+		if bStarts.Add(1) == 1 {
+			return errDecode
+		}
+		<-ctx.Done()
+		return nil
+	}
+
+	events := make(chan SupervisorEvent, 64)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sup := NewSupervisor(fastOptions(OneForAll, events),
+		Worker{Name: "a", Run: blockUntilDone(&aStarts)},
+		Worker{Name: "b", Run: failOnce},
+	)
+	done := make(chan error, 1)
+	go func() { done <- sup.Run(ctx) }()
+
+	restarted := map[string]bool{}
+	for len(restarted) < 2 {
+		e := <-events
+		if e.Kind == WorkerCrashed && !errors.Is(e.Err, errDecode) {
+			t.Errorf("Expected the returned error as the crash, got %v", e.Err)
+		}
+		if e.Kind == WorkerRestarted {
+			restarted[e.Worker] = true
+		}
+	}
+	for aStarts.Load() < 2 || bStarts.Load() < 2 {
+		runtime.Gosched()
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestSupervisorGivesUpAfterMaxRestarts(t *testing.T) {
+	var starts atomic.Int32
+	events := make(chan SupervisorEvent, 64)
+	sup := NewSupervisor(fastOptions(OneForOne, events),
+		Worker{Name: "poison", Run: func(ctx context.Context) error {
+			starts.Add(1)
+			panic("poison message")
+		}},
+	)
+
+	start := time.Now()
+	err := sup.Run(context.Background())
+
+	if !errors.Is(err, ErrTooManyRestarts) {
+		t.Fatalf("Expected ErrTooManyRestarts, got %v", err)
+	}
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "poison message" {
+		t.Errorf("Expected the last crash to be wrapped, got %v", err)
+	}
+	if starts.Load() != 4 {
+		t.Errorf("Expected 1 start and 3 restarts, got %d starts", starts.Load())
+	}
+	// Backoff: 1ms, 2ms, 4ms.
+	if elapsed := time.Since(start); elapsed < 7*time.Millisecond {
+		t.Errorf("Expected restarts to back off, finished in %v", elapsed)
+	}
+
+	close(events)
+	var delays []time.Duration
+	var last SupervisorEvent
+	for e := range events {
+		if e.Kind == WorkerRestarted {
+			delays = append(delays, e.Delay)
+		}
+		last = e
+	}
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond}
+	if len(delays) != len(want) || delays[0] != want[0] || delays[1] != want[1] || delays[2] != want[2] {
+		t.Errorf("Expected backoff %v, got %v", want, delays)
+	}
+	if last.Kind != SupervisorGaveUp {
+		t.Errorf("Expected the final event to be %v, got %v", SupervisorGaveUp, last.Kind)
+	}
+}
+
+func TestSupervisorReturnsWhenWorkersFinish(t *testing.T) {
+	sup := NewSupervisor(SupervisorOptions{},
+		Worker{Name: "migrate", Run: func(ctx context.Context) error { return nil }},
+		Worker{Name: "warmup", Run: func(ctx context.Context) error { return nil }},
+	)
+	if err := sup.Run(context.Background()); err != nil {
+		t.Errorf("Expected nil once every worker finished, got %v", err)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	sup := NewSupervisor(SupervisorOptions{MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+	tests := []struct {
+		recent int
+		want   time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 50 * time.Millisecond},
+		{30, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := sup.backoff(tt.recent); got != tt.want {
+			t.Errorf("backoff(%d): Expected %v, got %v", tt.recent, tt.want, got)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package stringsbytes
 
+import "go-playbook/basic/08-strings-bytes-and-runes/text"
+
 // Context: UTF-8 Correctness
 // You are writing a truncation middleware for a logging pipeline.
 // If a log message exceeds a certain character limit, you must truncate it
@@ -17,13 +19,11 @@ package stringsbytes
 // 4. Do not corrupt multi-byte characters!
 
 func Truncate(logMsg string, maxChars int) string {
-	// BUG: len(logMsg) measures BYTES.
-	// `logMsg[:maxChars]` slices BYTES.
-	// TODO: Fix this to safely truncate based on RUNES (characters).
-
-	if len(logMsg) <= maxChars {
-		return logMsg
+	// text.Prefix counts runes and only cuts on rune boundaries. Sanitize first:
+	// a log line with invalid UTF-8 would be rejected downstream even if never cut.
+	head, cut := text.Prefix(text.Sanitize(logMsg), maxChars, text.Runes)
+	if !cut {
+		return head
 	}
-
-	return logMsg[:maxChars] + "..."
+	return head + "..."
 }
@@ -0,0 +1,233 @@
+package stringsbytes
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVWriterQuoting(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"", `""`}, // A lone empty field must not become a blank line
+		{"a,b", `"a,b"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{"two\nlines", "\"two\nlines\""},
+		{"cr\r", "\"cr\r\""},
+		{" padded", `" padded"`},
+		{"東京", "東京"},
+	}
+	for _, tt := range tests {
+		var sb strings.Builder
+		cw := NewCSVWriter(&sb)
+		cw.String(tt.in)
+		cw.EndRow()
+		cw.Flush()
+
+		if got := strings.TrimSuffix(sb.String(), "\n"); got != tt.want {
+			t.Errorf("String(%q): Expected %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestCSVWriterRoundTrips(t *testing.T) {
+	when := time.Date(2024, 6, 1, 12, 30, 0, 500, time.FixedZone("BRT", -3*3600))
+
+	var sb strings.Builder
+	cw := NewCSVWriter(&sb)
+	cw.Comma = ';'
+	cw.UseCRLF = true
+	cw.Int(-42)
+	cw.Uint(math.MaxUint64)
+	cw.Float(0.1)
+	cw.Float(1e21)
+	cw.Bool(true)
+	cw.Time(when)
+	cw.String("semi;colon, \"quoted\"\nnewline")
+	cw.Time(time.Time{})
+	if err := cw.EndRow(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(sb.String(), "\r\n") {
+		t.Errorf("Expected CRLF row endings, got %q", sb.String())
+	}
+	r := csv.NewReader(strings.NewReader(sb.String()))
+	r.Comma = ';'
+	rec, err := r.Read()
+	if err != nil {
+		t.Fatalf("encoding/csv could not read our output %q: %v", sb.String(), err)
+	}
+	want := []string{"-42", "18446744073709551615", "0.1", "1e+21", "true", "2024-06-01T12:30:00.0000005-03:00", "semi;colon, \"quoted\"\nnewline", ""}
+	if !slices.Equal(rec, want) {
+		t.Errorf("Expected %q, got %q", want, rec)
+	}
+}
+
+func TestCSVWriterKeepsEmptyRows(t *testing.T) {
+	var sb strings.Builder
+	cw := NewCSVWriter(&sb)
+	for _, row := range [][]string{{"a"}, {""}, {"", ""}, {"b"}} {
+		for _, f := range row {
+			cw.String(f)
+		}
+		cw.EndRow()
+	}
+	cw.String("c")
+	cw.Flush() // Mid-row: the rest of the row must not look empty afterwards.
+	cw.EndRow()
+	cw.Flush()
+
+	if want := "a\n\"\"\n,\nb\nc\n"; sb.String() != want {
+		t.Fatalf("Expected %q, got %q", want, sb.String())
+	}
+	r := csv.NewReader(strings.NewReader(sb.String()))
+	r.FieldsPerRecord = -1
+	recs, err := r.ReadAll()
+	if err != nil || len(recs) != 5 {
+		t.Fatalf("Expected encoding/csv to read 5 rows, got %q (%v)", recs, err)
+	}
+}
+
+func TestCSVWriterRejectsInvalidDelimiter(t *testing.T) {
+	for _, comma := range []rune{'"', '\n', 0} {
+		cw := NewCSVWriter(io.Discard)
+		cw.Comma = comma
+		cw.Int(1)
+		if err := cw.EndRow(); !errors.Is(err, ErrInvalidDelimiter) {
+			t.Errorf("Comma %q: Expected ErrInvalidDelimiter, got %v", comma, err)
+		}
+	}
+}
+
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.n++
+	return 0, io.ErrShortWrite
+}
+
+func TestCSVWriterErrorsAreSticky(t *testing.T) {
+	fw := &failingWriter{}
+	cw := NewCSVWriter(fw)
+	cw.Int(1)
+	cw.EndRow()
+	if err := cw.Flush(); !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("Expected the write error, got %v", err)
+	}
+	cw.Int(2)
+	if err := cw.EndRow(); !errors.Is(err, io.ErrShortWrite) || cw.Flush() == nil || fw.n != 1 {
+		t.Errorf("Expected later calls to report the first error without writing, got %v after %d writes", err, fw.n)
+	}
+}
+
+type exportRow struct {
+	ID       int64     `csv:"id"`
+	Customer string    `csv:"customer"`
+	Amount   float64   `csv:"amount"`
+	Ratio    float32   `csv:"ratio"`
+	Paid     bool      `csv:"paid"`
+	Created  time.Time `csv:"created_at"`
+	Refund   *int      `csv:"refund"`
+	Internal string    `csv:"-"`
+	Region   string
+	notes    string
+}
+
+func TestWriteStruct(t *testing.T) {
+	refund := 7
+	rows := []exportRow{
+		{ID: 1, Customer: "Acme, Inc.", Amount: 19.99, Ratio: 0.1, Paid: true, Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Internal: "x", Region: "eu", notes: "y"},
+		{ID: 2, Customer: "Bob", Amount: 5, Refund: &refund},
+	}
+
+	var sb strings.Builder
+	cw := NewCSVWriter(&sb)
+	if err := WriteHeader[exportRow](cw); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := WriteStruct(cw, row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cw.Flush()
+
+	want := "id,customer,amount,ratio,paid,created_at,refund,Region\n" +
+		"1,\"Acme, Inc.\",19.99,0.1,true,2024-01-02T03:04:05Z,,eu\n" +
+		"2,Bob,5,0,false,,7,\n"
+	if sb.String() != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, sb.String())
+	}
+
+	type nested struct{ Inner exportRow }
+	if err := WriteStruct(cw, nested{}); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Expected ErrUnsupportedType for a nested struct, got %v", err)
+	}
+	if err := WriteStruct(cw, 42); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Expected ErrUnsupportedType for a non-struct, got %v", err)
+	}
+}
+
+func TestCSVWriterBoundedAllocsPerRow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation guard")
+	}
+	row := exportRow{ID: 1, Customer: `Acme "Tools", Inc.`, Amount: 19.99, Created: time.Now()}
+	cw := NewCSVWriter(io.Discard)
+
+	primitive := testing.AllocsPerRun(10_000, func() {
+		cw.Int(row.ID)
+		cw.String(row.Customer)
+		cw.Float(row.Amount)
+		cw.Bool(row.Paid)
+		cw.Time(row.Created)
+		cw.EndRow()
+	})
+	if primitive != 0 {
+		t.Errorf("Expected 0 allocations per row of primitives, got %v", primitive)
+	}
+
+	// Passing the row by value makes it escape once into reflection; nothing else allocates.
+	reflected := testing.AllocsPerRun(10_000, func() { WriteStruct(cw, row) })
+	if reflected > 1 {
+		t.Errorf("Expected at most 1 allocation per WriteStruct row, got %v", reflected)
+	}
+}
+
+func BenchmarkCSVWriterRow(b *testing.B) {
+	b.ReportAllocs()
+	cw := NewCSVWriter(io.Discard)
+	created := time.Now()
+	for i := 0; b.Loop(); i++ {
+		cw.Int(int64(i))
+		cw.String("Acme, Inc.")
+		cw.Float(19.99)
+		cw.Bool(true)
+		cw.Time(created)
+		cw.EndRow()
+	}
+	cw.Flush()
+}
+
+func BenchmarkWriteStruct(b *testing.B) {
+	b.ReportAllocs()
+	cw := NewCSVWriter(io.Discard)
+	row := exportRow{Customer: "Acme, Inc.", Amount: 19.99, Created: time.Now(), Region: "eu"}
+	for i := 0; b.Loop(); i++ {
+		row.ID = int64(i)
+		WriteStruct(cw, row)
+	}
+	cw.Flush()
+}
@@ -0,0 +1,192 @@
+package text
+
+import (
+	"iter"
+	"unicode"
+	"unicode/utf8"
+)
+
+// graphemeClass is the subset of UAX #29 Grapheme_Cluster_Break values we distinguish.
+type graphemeClass int
+
+const (
+	gcOther graphemeClass = iota
+	gcCR
+	gcLF
+	gcControl
+	gcExtend
+	gcZWJ
+	gcRegionalIndicator
+	gcSpacingMark
+	gcL
+	gcV
+	gcT
+	gcLV
+	gcLVT
+	gcPictographic // Extended_Pictographic, tracked for GB11
+)
+
+var pictographicRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00a9, Hi: 0x00a9, Stride: 1},
+		{Lo: 0x00ae, Hi: 0x00ae, Stride: 1},
+		{Lo: 0x203c, Hi: 0x203c, Stride: 1},
+		{Lo: 0x2049, Hi: 0x2049, Stride: 1},
+		{Lo: 0x2122, Hi: 0x2122, Stride: 1},
+		{Lo: 0x2139, Hi: 0x2139, Stride: 1},
+		{Lo: 0x2194, Hi: 0x21aa, Stride: 1},
+		{Lo: 0x231a, Hi: 0x23ff, Stride: 1},
+		{Lo: 0x24c2, Hi: 0x24c2, Stride: 1},
+		{Lo: 0x25aa, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2b05, Hi: 0x2b55, Stride: 1},
+		{Lo: 0x3030, Hi: 0x3030, Stride: 1},
+		{Lo: 0x303d, Hi: 0x303d, Stride: 1},
+		{Lo: 0x3297, Hi: 0x3299, Stride: 2},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f000, Hi: 0x1f1e5, Stride: 1},
+		{Lo: 0x1f200, Hi: 0x1f3fa, Stride: 1},
+		{Lo: 0x1f400, Hi: 0x1faff, Stride: 1},
+		{Lo: 0x1fc00, Hi: 0x1fffd, Stride: 1},
+	},
+}
+
+func classify(r rune) graphemeClass {
+	switch {
+	case r == '\r':
+		return gcCR
+	case r == '\n':
+		return gcLF
+	case r == 0x200d:
+		return gcZWJ
+	case r == 0x200c, r >= 0x1f3fb && r <= 0x1f3ff, // ZWNJ and emoji skin-tone modifiers
+		r >= 0xe0020 && r <= 0xe007f, // Tags (subdivision flags); Cf, but Extend here
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Other_Grapheme_Extend):
+		return gcExtend
+	case unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp):
+		return gcControl
+	case r >= 0x1f1e6 && r <= 0x1f1ff:
+		return gcRegionalIndicator
+	case unicode.Is(unicode.Mc, r):
+		return gcSpacingMark
+	case r >= 0x1100 && r <= 0x115f, r >= 0xa960 && r <= 0xa97c:
+		return gcL
+	case r >= 0x1160 && r <= 0x11a7, r >= 0xd7b0 && r <= 0xd7c6:
+		return gcV
+	case r >= 0x11a8 && r <= 0x11ff, r >= 0xd7cb && r <= 0xd7fb:
+		return gcT
+	case r >= 0xac00 && r <= 0xd7a3:
+		if (r-0xac00)%28 == 0 {
+			return gcLV
+		}
+		return gcLVT
+	case unicode.Is(pictographicRanges, r):
+		return gcPictographic
+	default:
+		return gcOther
+	}
+}
+
+// graphemeBreak reports whether there is a cluster boundary between prev and cur.
+// pictSeq is true when prev ends "ExtPict Extend* ZWJ"; riCount is the number of
+// consecutive regional indicators ending at prev.
+func graphemeBreak(prev, cur graphemeClass, pictSeq bool, riCount int) bool {
+	switch {
+	case prev == gcCR && cur == gcLF: // GB3
+		return false
+	case prev == gcCR, prev == gcLF, prev == gcControl: // GB4
+		return true
+	case cur == gcCR, cur == gcLF, cur == gcControl: // GB5
+		return true
+	case prev == gcL && (cur == gcL || cur == gcV || cur == gcLV || cur == gcLVT): // GB6
+		return false
+	case (prev == gcLV || prev == gcV) && (cur == gcV || cur == gcT): // GB7
+		return false
+	case (prev == gcLVT || prev == gcT) && cur == gcT: // GB8
+		return false
+	case cur == gcExtend, cur == gcZWJ, cur == gcSpacingMark: // GB9, GB9a
+		return false
+	case prev == gcZWJ && cur == gcPictographic && pictSeq: // GB11
+		return false
+	case prev == gcRegionalIndicator && cur == gcRegionalIndicator: // GB12, GB13
+		return riCount%2 == 0
+	default: // GB999
+		return true
+	}
+}
+
+// Clusters yields the extended grapheme clusters of s: what a reader perceives as
+// one character. The segmentation implements the UAX #29 rules that matter in
+// practice: CR LF, controls, combining and spacing marks, emoji modifiers, ZWJ emoji
+// sequences, regional indicator pairs (flags) and Hangul syllables. Invalid UTF-8
+// bytes are yielded one at a time.
+func Clusters(s string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for len(s) > 0 {
+			n := nextGrapheme(s)
+			if !yield(s[:n]) {
+				return
+			}
+			s = s[n:]
+		}
+	}
+}
+
+// GraphemeCount returns the number of grapheme clusters in s.
+func GraphemeCount(s string) int {
+	n := 0
+	for len(s) > 0 {
+		s = s[nextGrapheme(s):]
+		n++
+	}
+	return n
+}
+
+// nextGrapheme returns the byte length of the first grapheme cluster of s.
+func nextGrapheme(s string) int {
+	prev, i := classifyAt(s)
+	pictSeq := prev == gcPictographic // Inside "ExtPict Extend*" or "ExtPict Extend* ZWJ"
+	riCount := 0
+	if prev == gcRegionalIndicator {
+		riCount = 1
+	}
+
+	for i < len(s) {
+		cur, size := classifyAt(s[i:])
+		if graphemeBreak(prev, cur, pictSeq && prev == gcZWJ, riCount) {
+			return i
+		}
+
+		switch cur {
+		case gcPictographic:
+			pictSeq = true
+		case gcExtend, gcZWJ:
+			// Extend continues an "ExtPict Extend*" prefix; only one ZWJ may close it.
+			pictSeq = pictSeq && prev != gcZWJ
+		default:
+			pictSeq = false
+		}
+		if cur == gcRegionalIndicator {
+			riCount++
+		} else {
+			riCount = 0
+		}
+		prev = cur
+		i += size
+	}
+	return i
+}
+
+// classifyAt classifies the first rune of s. A stray byte is a Control, which forces
+// a boundary on both sides of it.
+func classifyAt(s string) (graphemeClass, int) {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError && size == 1 {
+		return gcControl, 1
+	}
+	return classify(r), size
+}
+
+func isRegionalIndicator(r rune) bool { return classify(r) == gcRegionalIndicator }
+func isPictographic(r rune) bool      { return classify(r) == gcPictographic }
@@ -0,0 +1,158 @@
+// Package text truncates and measures user-supplied Unicode text without corrupting it.
+//
+// Slicing a string by bytes can cut a multi-byte rune in half. Slicing by runes is
+// safe for UTF-8 but still tears characters apart: "é" may be "e" plus a combining
+// accent, and a family emoji is seven runes joined by ZWJ. And in a terminal table,
+// "東京" takes four columns, not two.
+//
+// Every function here cuts only at grapheme cluster boundaries (or rune boundaries,
+// for Runes), and measures in one of three units:
+//
+//   - Runes: Unicode code points, the unit of most storage limits.
+//   - Graphemes: user-perceived characters.
+//   - Cells: terminal display width, where East Asian wide characters and emoji
+//     count as 2 and combining marks as 0.
+//
+// Input is sanitized first: invalid UTF-8 becomes U+FFFD, so the output is always
+// valid UTF-8 even when the input was not.
+package text
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Unit is what a length limit counts.
+type Unit int
+
+const (
+	Runes Unit = iota
+	Graphemes
+	Cells
+)
+
+func (u Unit) String() string {
+	switch u {
+	case Runes:
+		return "runes"
+	case Graphemes:
+		return "graphemes"
+	case Cells:
+		return "cells"
+	}
+	return fmt.Sprintf("Unit(%d)", int(u))
+}
+
+// Sanitize replaces every run of invalid UTF-8 bytes with U+FFFD. Valid input is
+// returned as is, without allocating.
+func Sanitize(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	return strings.ToValidUTF8(s, "�")
+}
+
+// Measure returns the length of s in unit u.
+func Measure(s string, u Unit) int {
+	switch u {
+	case Graphemes:
+		return GraphemeCount(s)
+	case Cells:
+		return Width(s)
+	}
+	return utf8.RuneCountInString(s)
+}
+
+// next returns the byte length and size in unit u of the first segment of s.
+func next(s string, u Unit) (n, size int) {
+	if u == Runes {
+		_, n = utf8.DecodeRuneInString(s)
+		return n, 1
+	}
+	n = nextGrapheme(s)
+	if u == Cells {
+		return n, clusterWidth(s[:n])
+	}
+	return n, 1
+}
+
+// Prefix returns the longest prefix of s that fits in limit units, and whether
+// anything was cut. It never splits a rune or, for Graphemes and Cells, a cluster.
+func Prefix(s string, limit int, u Unit) (string, bool) {
+	used, i := 0, 0
+	for i < len(s) {
+		n, size := next(s[i:], u)
+		if used+size > limit {
+			return s[:i], true
+		}
+		used += size
+		i += n
+	}
+	return s, false
+}
+
+// Suffix returns the longest suffix of s that fits in limit units, and whether
+// anything was cut.
+func Suffix(s string, limit int, u Unit) (string, bool) {
+	// Segmentation only runs forwards: record the boundaries, then walk them back.
+	var bounds []int
+	var sizes []int
+	for i := 0; i < len(s); {
+		n, size := next(s[i:], u)
+		bounds = append(bounds, i)
+		sizes = append(sizes, size)
+		i += n
+	}
+
+	used, start := 0, len(s)
+	for k := len(bounds) - 1; k >= 0; k-- {
+		if used+sizes[k] > limit {
+			return s[start:], true
+		}
+		used += sizes[k]
+		start = bounds[k]
+	}
+	return s, false
+}
+
+// Truncator shortens text to a limit, marking the cut with an ellipsis. The zero
+// value truncates by runes at the end, with no ellipsis.
+type Truncator struct {
+	Unit     Unit
+	Ellipsis string // e.g. "…" or "..."; counts towards the limit
+
+	// Middle keeps both ends and cuts out the middle, for paths and identifiers
+	// whose most useful part is the end: "/var/log/…/api/2024-06-01.log".
+	// The tail gets the larger half of the budget.
+	Middle bool
+}
+
+// Truncate returns s sanitized and, if it is longer than limit units, shortened so
+// that the result, ellipsis included, fits in limit. If the limit cannot fit even the
+// ellipsis, the text is cut without one.
+func (t Truncator) Truncate(s string, limit int) string {
+	s = Sanitize(s)
+	if limit <= 0 {
+		return ""
+	}
+	if Measure(s, t.Unit) <= limit {
+		return s
+	}
+
+	ellipsis := t.Ellipsis
+	budget := limit - Measure(ellipsis, t.Unit)
+	if budget < 0 {
+		ellipsis, budget = "", limit
+	}
+
+	if !t.Middle {
+		head, _ := Prefix(s, budget, t.Unit)
+		return head + ellipsis
+	}
+	tailBudget := (budget + 1) / 2
+	tail, _ := Suffix(s, tailBudget, t.Unit)
+	// The head gets whatever the tail left unused, e.g. when a wide character did not fit.
+	head, _ := Prefix(s[:len(s)-len(tail)], budget-Measure(tail, t.Unit), t.Unit)
+	return head + ellipsis + tail
+}
@@ -0,0 +1,144 @@
+package text
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const (
+	family   = "\U0001F468\u200d\U0001F469\u200d\U0001F467" // Three emoji joined by ZWJ
+	thumbsUp = "\U0001F44D\U0001F3FD"                       // Emoji with a skin-tone modifier
+	flagJP   = "\U0001F1EF\U0001F1F5"                       // Two regional indicators
+	eAcute   = "e\u0301"                                    // "e" + COMBINING ACUTE ACCENT
+
+	flagScotland = "\U0001F3F4\U000E0067\U000E0062\U000E0073\U000E0063\U000E0074\U000E007F" // Black flag + tag sequence
+)
+
+func TestClusters(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"abc", []string{"a", "b", "c"}},
+		{"caf" + eAcute + "!", []string{"c", "a", "f", eAcute, "!"}},
+		{family + thumbsUp, []string{family, thumbsUp}},
+		{flagJP + "🇺🇸🇫", []string{flagJP, "🇺🇸", "🇫"}},
+		{"a\r\nb", []string{"a", "\r\n", "b"}},
+		{"\u1112\u1161\u11ab", []string{"\u1112\u1161\u11ab"}}, // Conjoining jamo for 한
+		{"\u2764\ufe0fx", []string{"\u2764\ufe0f", "x"}},
+		{"a\xffb", []string{"a", "\xff", "b"}},
+		{flagScotland + "!", []string{flagScotland, "!"}},
+	}
+	for _, tt := range tests {
+		got := slices.Collect(Clusters(tt.in))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Clusters(%q): Expected %q, got %q", tt.in, tt.want, got)
+		}
+		if n := GraphemeCount(tt.in); n != len(tt.want) {
+			t.Errorf("GraphemeCount(%q): Expected %d, got %d", tt.in, len(tt.want), n)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"hello", 5},
+		{"東京", 4},
+		{"ｱｲｳ", 3}, // Halfwidth katakana
+		{"ＡＢ", 4},  // Fullwidth latin
+		{"한국어", 6},
+		{eAcute, 1},
+		{family, 2},
+		{thumbsUp, 2},
+		{flagJP, 2},
+		{"\u2764", 1},
+		{"\u2764\ufe0f", 2}, // VS16 requests emoji presentation
+		{"a\tb", 2},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := Width(tt.in); got != tt.want {
+			t.Errorf("Width(%q): Expected %d, got %d", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	if s := "ok 東京"; Sanitize(s) != s {
+		t.Errorf("Expected valid input unchanged")
+	}
+	got := Sanitize("bad\xff\xfe utf8 \xe6\x9d")
+	if !utf8.ValidString(got) || got != "bad� utf8 �" {
+		t.Errorf("Expected invalid runs replaced by U+FFFD, got %q", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		t     Truncator
+		in    string
+		limit int
+		want  string
+	}{
+		{"fits", Truncator{Ellipsis: "…"}, "hello", 5, "hello"},
+		{"runes", Truncator{Ellipsis: "…"}, "こんにちは世界", 5, "こんにち…"},
+		{"runes split clusters", Truncator{}, "caf" + eAcute, 4, "cafe"},
+		{"graphemes keep clusters", Truncator{Unit: Graphemes, Ellipsis: "…"}, "caf" + eAcute + "s!", 5, "caf" + eAcute + "…"},
+		{"graphemes emoji", Truncator{Unit: Graphemes, Ellipsis: "…"}, family + thumbsUp + flagJP + "ok", 3, family + thumbsUp + "…"},
+		{"cells", Truncator{Unit: Cells, Ellipsis: "…"}, "東京都庁", 5, "東京…"},
+		{"cells wide does not fit", Truncator{Unit: Cells, Ellipsis: "…"}, "a東京都", 4, "a東…"},
+		{"ascii ellipsis", Truncator{Unit: Cells, Ellipsis: "..."}, "abcdefgh", 6, "abc..."},
+		{"ellipsis too long", Truncator{Ellipsis: "[truncated]"}, "abcdefgh", 3, "abc"},
+		{"invalid input", Truncator{Ellipsis: "…"}, "ab\xffcdef", 4, "ab�…"},
+		{"zero limit", Truncator{Ellipsis: "…"}, "abc", 0, ""},
+		{"middle path", Truncator{Ellipsis: "…", Middle: true}, "/var/log/services/api/2024-06-01.log", 20, "/var/log/…-06-01.log"},
+		{"middle cells", Truncator{Unit: Cells, Ellipsis: "…", Middle: true}, "東京/大阪/名古屋/札幌.txt", 13, "東京/…幌.txt"},
+	}
+	for _, tt := range tests {
+		got := tt.t.Truncate(tt.in, tt.limit)
+		if got != tt.want {
+			t.Errorf("%s: Expected %q, got %q", tt.name, tt.want, got)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("%s: Produced invalid UTF-8 %q", tt.name, got)
+		}
+		if n := Measure(got, tt.t.Unit); n > tt.limit {
+			t.Errorf("%s: Result %q measures %d %v, over the limit %d", tt.name, got, n, tt.t.Unit, tt.limit)
+		}
+	}
+}
+
+func TestTruncateNeverSplitsRunes(t *testing.T) {
+	in := strings.Repeat("日本語テキスト🚀", 20)
+	for _, unit := range []Unit{Runes, Graphemes, Cells} {
+		for _, middle := range []bool{false, true} {
+			tr := Truncator{Unit: unit, Ellipsis: "…", Middle: middle}
+			for limit := range 40 {
+				got := tr.Truncate(in, limit)
+				if !utf8.ValidString(got) || Measure(got, unit) > limit {
+					t.Fatalf("%v middle=%v limit %d: got %q", unit, middle, limit, got)
+				}
+			}
+		}
+	}
+}
+
+func TestPrefixAndSuffix(t *testing.T) {
+	head, cut := Prefix("東京タワー", 5, Cells)
+	if head != "東京" || !cut {
+		t.Errorf("Prefix: Expected %q cut, got %q (%v)", "東京", head, cut)
+	}
+	tail, cut := Suffix("東京タワー", 5, Cells)
+	if tail != "ワー" || !cut {
+		t.Errorf("Suffix: Expected %q cut, got %q (%v)", "ワー", tail, cut)
+	}
+	if s, cut := Suffix("abc", 3, Runes); s != "abc" || cut {
+		t.Errorf("Suffix: Expected the whole string uncut, got %q (%v)", s, cut)
+	}
+}
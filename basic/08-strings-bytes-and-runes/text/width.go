@@ -0,0 +1,125 @@
+package text
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// wide lists the East Asian Wide (W) and Fullwidth (F) ranges, plus the emoji that
+// terminals render in two cells. It follows UAX #11 closely enough for tables and
+// logs; it is not a full copy of EastAsianWidth.txt.
+var wide = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x1100, 0x115F, 1}, // Hangul Jamo initial consonants
+		{0x231A, 0x231B, 1},
+		{0x2329, 0x232A, 1},
+		{0x23E9, 0x23EC, 1},
+		{0x23F0, 0x23F0, 1},
+		{0x23F3, 0x23F3, 1},
+		{0x25FD, 0x25FE, 1},
+		{0x2614, 0x2615, 1},
+		{0x2648, 0x2653, 1},
+		{0x267F, 0x267F, 1},
+		{0x2693, 0x2693, 1},
+		{0x26A1, 0x26A1, 1},
+		{0x26AA, 0x26AB, 1},
+		{0x26BD, 0x26BE, 1},
+		{0x26C4, 0x26C5, 1},
+		{0x26CE, 0x26CE, 1},
+		{0x26D4, 0x26D4, 1},
+		{0x26EA, 0x26EA, 1},
+		{0x26F2, 0x26F3, 1},
+		{0x26F5, 0x26F5, 1},
+		{0x26FA, 0x26FA, 1},
+		{0x26FD, 0x26FD, 1},
+		{0x2705, 0x2705, 1},
+		{0x270A, 0x270B, 1},
+		{0x2728, 0x2728, 1},
+		{0x274C, 0x274C, 1},
+		{0x274E, 0x274E, 1},
+		{0x2753, 0x2755, 1},
+		{0x2757, 0x2757, 1},
+		{0x2795, 0x2797, 1},
+		{0x27B0, 0x27B0, 1},
+		{0x27BF, 0x27BF, 1},
+		{0x2B1B, 0x2B1C, 1},
+		{0x2B50, 0x2B50, 1},
+		{0x2B55, 0x2B55, 1},
+		{0x2E80, 0x303E, 1}, // CJK radicals, punctuation
+		{0x3041, 0x33FF, 1}, // Hiragana, Katakana, CJK compatibility
+		{0x3400, 0x4DBF, 1}, // CJK Extension A
+		{0x4E00, 0x9FFF, 1}, // CJK Unified Ideographs
+		{0xA000, 0xA4CF, 1}, // Yi
+		{0xA960, 0xA97F, 1}, // Hangul Jamo Extended-A
+		{0xAC00, 0xD7A3, 1}, // Hangul syllables
+		{0xF900, 0xFAFF, 1}, // CJK compatibility ideographs
+		{0xFE10, 0xFE19, 1}, // Vertical forms
+		{0xFE30, 0xFE6F, 1}, // CJK compatibility forms, small forms
+		{0xFF00, 0xFF60, 1}, // Fullwidth forms
+		{0xFFE0, 0xFFE6, 1},
+	},
+	R32: []unicode.Range32{
+		{0x16FE0, 0x16FE4, 1},
+		{0x17000, 0x18AFF, 1}, // Tangut
+		{0x1B000, 0x1B2FF, 1}, // Kana supplements
+		{0x1F004, 0x1F004, 1},
+		{0x1F0CF, 0x1F0CF, 1},
+		{0x1F18E, 0x1F18E, 1},
+		{0x1F191, 0x1F19A, 1},
+		{0x1F200, 0x1F251, 1}, // Enclosed ideographic supplement
+		{0x1F300, 0x1F64F, 1}, // Pictographs, emoticons
+		{0x1F680, 0x1F6FF, 1}, // Transport and map symbols
+		{0x1F7E0, 0x1F7EB, 1},
+		{0x1F90C, 0x1F9FF, 1}, // Supplemental symbols and pictographs
+		{0x1FA70, 0x1FAFF, 1},
+		{0x20000, 0x2FFFD, 1}, // CJK Extensions B-F
+		{0x30000, 0x3FFFD, 1}, // CJK Extension G and beyond
+	},
+}
+
+// RuneWidth returns the number of terminal cells r occupies: 0 for control
+// characters, combining marks and format characters such as ZWJ, 2 for East Asian
+// wide characters and emoji, and 1 otherwise.
+func RuneWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.IsControl(r):
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1160 && r <= 0x11FF: // Hangul medial vowels and final consonants join the syllable.
+		return 0
+	case r < 0x1100:
+		return 1
+	case unicode.Is(wide, r):
+		return 2
+	}
+	return 1
+}
+
+// Width returns the display width of s, measured per grapheme cluster so that a
+// base character and its combining marks, or a ZWJ emoji sequence, count once.
+func Width(s string) int {
+	w := 0
+	for g := range Clusters(s) {
+		w += clusterWidth(g)
+	}
+	return w
+}
+
+// clusterWidth is the width of its base character, widened to 2 for emoji
+// presentation (VS16) and flags.
+func clusterWidth(g string) int {
+	r, size := utf8.DecodeRuneInString(g)
+	w := RuneWidth(r)
+	if isRegionalIndicator(r) {
+		return 2
+	}
+	if w == 1 && isPictographic(r) {
+		for _, c := range g[size:] {
+			if c == 0xFE0F {
+				return 2
+			}
+		}
+	}
+	return w
+}
@@ -0,0 +1,69 @@
+// Package clock lets time-based code run against the real clock in production and a
+// manually advanced clock in tests.
+//
+// Code that calls time.Now, time.After or time.Sleep directly can only be tested by
+// waiting, which makes the suite slow, and by guessing at margins, which makes it
+// flaky. Depending on a Clock instead costs one field. Tests then substitute a Fake
+// and move time forward explicitly:
+//
+//	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	go worker(clk)
+//	clk.BlockUntil(1)          // The worker is now waiting on a timer...
+//	clk.Advance(time.Minute)   // ...which fires, without a minute passing.
+//
+// Timers follow the Go 1.23 semantics: after Stop or Reset returns, no stale value
+// is received from the channel.
+package clock
+
+import "time"
+
+// Clock is the subset of package time that depends on the passage of time.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a *time.Timer. C is nil for timers created by AfterFunc.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is a *time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Real is the system clock.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
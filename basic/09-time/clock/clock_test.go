@@ -0,0 +1,160 @@
+package clock
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeTimerFiresOnAdvance(t *testing.T) {
+	clk := NewFake(epoch)
+	timer := clk.NewTimer(10 * time.Second)
+
+	clk.Advance(9 * time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("Expected the timer not to fire before its deadline")
+	default:
+	}
+
+	clk.Advance(5 * time.Second)
+	select {
+	case at := <-timer.C():
+		if !at.Equal(epoch.Add(10 * time.Second)) {
+			t.Errorf("Expected the timer to fire at its deadline, got %v", at)
+		}
+	default:
+		t.Fatal("Expected the timer to fire")
+	}
+	if got := clk.Now(); !got.Equal(epoch.Add(14 * time.Second)) {
+		t.Errorf("Expected Now to be the advanced time, got %v", got)
+	}
+	if clk.Waiters() != 0 {
+		t.Errorf("Expected a fired timer to stop waiting, got %d waiters", clk.Waiters())
+	}
+}
+
+func TestFakeTimerStopAndReset(t *testing.T) {
+	clk := NewFake(epoch)
+	timer := clk.NewTimer(time.Second)
+
+	if !timer.Stop() {
+		t.Error("Expected Stop to report an active timer")
+	}
+	clk.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Fatal("Expected a stopped timer not to fire")
+	default:
+	}
+
+	timer.Reset(time.Minute)
+	clk.Advance(time.Minute)
+	timer.Reset(time.Minute) // The undelivered tick is discarded, as in Go 1.23+.
+	select {
+	case <-timer.C():
+		t.Fatal("Expected Reset to discard the stale value")
+	default:
+	}
+	if !timer.Stop() || timer.Stop() {
+		t.Error("Expected Stop to report true once, then false")
+	}
+}
+
+func TestFakeTickerFiresEveryPeriod(t *testing.T) {
+	clk := NewFake(epoch)
+	ticker := clk.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var ticks []time.Time
+	for range 3 {
+		clk.Advance(time.Second)
+		ticks = append(ticks, <-ticker.C())
+	}
+	for i, at := range ticks {
+		if want := epoch.Add(time.Duration(i+1) * time.Second); !at.Equal(want) {
+			t.Errorf("Tick %d: Expected %v, got %v", i, want, at)
+		}
+	}
+
+	clk.Advance(10 * time.Second) // Unread ticks are dropped, not queued.
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Error("Expected only one buffered tick")
+	default:
+	}
+
+	ticker.Reset(time.Minute)
+	clk.Advance(59 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Error("Expected Reset to change the period")
+	default:
+	}
+}
+
+func TestFakeAfterFuncObservesScheduledTime(t *testing.T) {
+	clk := NewFake(epoch)
+
+	var mu sync.Mutex
+	var fired []time.Duration
+	var wg sync.WaitGroup
+	for _, d := range []time.Duration{3 * time.Second, time.Second, 2 * time.Second} {
+		wg.Add(1)
+		clk.AfterFunc(d, func() {
+			defer wg.Done()
+			mu.Lock()
+			fired = append(fired, d)
+			mu.Unlock()
+		})
+	}
+	cancelled := clk.AfterFunc(time.Second, func() { t.Error("Expected a stopped AfterFunc not to run") })
+	cancelled.Stop()
+
+	clk.Advance(5 * time.Second)
+	wg.Wait()
+	if len(fired) != 3 {
+		t.Errorf("Expected 3 callbacks, got %v", fired)
+	}
+}
+
+func TestFakeSleepAndBlockUntil(t *testing.T) {
+	clk := NewFake(epoch)
+
+	done := make(chan time.Time)
+	for range 2 {
+		go func() {
+			clk.Sleep(time.Hour)
+			done <- clk.Now()
+		}()
+	}
+
+	clk.BlockUntil(2) // Both goroutines are asleep; advancing now cannot race them.
+	clk.Advance(time.Hour)
+	for range 2 {
+		if at := <-done; at.Before(epoch.Add(time.Hour)) {
+			t.Errorf("Expected to wake after the hour, woke at %v", at)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := clk.BlockUntilContext(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("Expected BlockUntilContext to give up, got %v", err)
+	}
+}
+
+func TestRealClockSatisfiesInterface(t *testing.T) {
+	timer := Real.NewTimer(time.Millisecond)
+	<-timer.C()
+	ticker := Real.NewTicker(time.Millisecond)
+	<-ticker.C()
+	ticker.Stop()
+	if Real.Since(Real.Now()) < 0 {
+		t.Error("Expected a non-negative duration from the real clock")
+	}
+}
@@ -0,0 +1,233 @@
+package clock
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when told to. It is safe for concurrent use.
+//
+// Every pending timer, ticker, After channel and Sleep is a waiter. Advance fires the
+// waiters that fall due in deadline order, stepping Now to each deadline as it goes,
+// so a ticker fires once per period crossed and callbacks observe the time they were
+// scheduled for. BlockUntil lets a test wait until the code under test has actually
+// started waiting before advancing.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeTimer
+	seq     uint64        // Tie-breaker: waiters due at the same instant fire in creation order
+	changed chan struct{} // Closed and replaced whenever waiters is modified
+}
+
+// NewFake returns a fake clock reading start.
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start, changed: make(chan struct{})}
+}
+
+// Now returns the fake's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration { return f.Now().Sub(t) }
+
+// After returns a channel that receives the fake time once d has been advanced past.
+func (f *Fake) After(d time.Duration) <-chan time.Time { return f.NewTimer(d).C() }
+
+// Sleep blocks until the clock has been advanced by d.
+func (f *Fake) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	<-f.NewTimer(d).C()
+}
+
+// NewTimer creates a timer that fires once the clock is advanced by d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// AfterFunc calls fn in its own goroutine once the clock is advanced by d.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{clock: f, fn: fn}
+	t.Reset(d)
+	return t
+}
+
+// NewTicker creates a ticker that fires every d of advanced time. Like a real
+// ticker, it drops ticks the receiver is not keeping up with.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+	return fakeTicker{t}
+}
+
+// Advance moves the clock forward by d, firing every waiter that falls due.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	target := f.now.Add(d)
+	for len(f.waiters) > 0 && !f.waiters[0].deadline.After(target) {
+		t := f.waiters[0]
+		f.now = t.deadline
+		f.removeLocked(t)
+		if t.period > 0 {
+			t.deadline = t.deadline.Add(t.period)
+			f.addLocked(t)
+		}
+		t.fire(f.now)
+	}
+	f.now = target
+}
+
+// Waiters returns the number of pending timers, tickers, After channels and sleeps.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil blocks until at least n waiters are pending.
+func (f *Fake) BlockUntil(n int) {
+	f.BlockUntilContext(context.Background(), n)
+}
+
+// BlockUntilContext is BlockUntil with a way out: it returns ctx.Err() if ctx is
+// done first.
+func (f *Fake) BlockUntilContext(ctx context.Context, n int) error {
+	for {
+		f.mu.Lock()
+		count, changed := len(f.waiters), f.changed
+		f.mu.Unlock()
+		if count >= n {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (f *Fake) addLocked(t *fakeTimer) {
+	f.seq++
+	t.seq = f.seq
+	i, _ := slices.BinarySearchFunc(f.waiters, t, compareDeadline)
+	f.waiters = slices.Insert(f.waiters, i, t)
+	f.notifyLocked()
+}
+
+// removeLocked removes t and reports whether it was pending.
+func (f *Fake) removeLocked(t *fakeTimer) bool {
+	i := slices.Index(f.waiters, t)
+	if i < 0 {
+		return false
+	}
+	f.waiters = slices.Delete(f.waiters, i, i+1)
+	f.notifyLocked()
+	return true
+}
+
+func (f *Fake) notifyLocked() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func compareDeadline(a, b *fakeTimer) int {
+	if c := a.deadline.Compare(b.deadline); c != 0 {
+		return c
+	}
+	switch {
+	case a.seq < b.seq:
+		return -1
+	case a.seq > b.seq:
+		return 1
+	}
+	return 0
+}
+
+// fakeTimer backs timers, AfterFunc timers and tickers.
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time // nil for AfterFunc
+	fn       func()
+	period   time.Duration // Non-zero for tickers
+	deadline time.Time
+	seq      uint64
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) fire(now time.Time) {
+	if t.fn != nil {
+		go t.fn()
+		return
+	}
+	select {
+	case t.c <- now:
+	default: // The previous value was not received yet: drop, like a real ticker.
+	}
+}
+
+// drain discards an undelivered value, so no stale tick survives Stop or Reset.
+func (t *fakeTimer) drain() {
+	if t.c == nil {
+		return
+	}
+	select {
+	case <-t.c:
+	default:
+	}
+}
+
+func (t *fakeTimer) Stop() bool {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t.drain()
+	return f.removeLocked(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t.drain()
+	wasActive := f.removeLocked(t)
+	t.deadline = f.now.Add(d)
+	if d <= 0 && t.period == 0 {
+		t.fire(f.now) // Fires immediately, as time.NewTimer(0) does.
+		return wasActive
+	}
+	f.addLocked(t)
+	return wasActive
+}
+
+// fakeTicker adapts fakeTimer to the Ticker method set.
+type fakeTicker struct{ t *fakeTimer }
+
+func (k fakeTicker) C() <-chan time.Time { return k.t.c }
+func (k fakeTicker) Stop()               { k.t.Stop() }
+
+func (k fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	k.t.clock.mu.Lock()
+	k.t.period = d
+	k.t.clock.mu.Unlock()
+	k.t.Reset(d)
+}
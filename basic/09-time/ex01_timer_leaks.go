@@ -3,6 +3,8 @@ package timeutil
 import (
 	"errors"
 	"time"
+
+	"go-playbook/basic/09-time/clock"
 )
 
 // Context: Timer Leaks in select blocks
@@ -21,18 +23,25 @@ import (
 
 var ErrTimeout = errors.New("request timed out")
 
+// FetchTimeout bounds how long FetchWithTimeout waits for a response.
+const FetchTimeout = 30 * time.Second
+
 func FetchWithTimeout(respCh <-chan string) (string, error) {
-	// BUG: Large timer leak in the fast-path!
-	// TODO: Replace time.After with a properly managed time.NewTimer.
-	// Ensure the timer is stopped when the function exits so the GC can clean it up.
+	return FetchWithClock(clock.Real, respCh)
+}
 
-	timeout := 30 * time.Second
+// FetchWithClock is FetchWithTimeout on an explicit clock, so tests can reach the
+// timeout without waiting 30 seconds and can check that the timer was released.
+func FetchWithClock(clk clock.Clock, respCh <-chan string) (string, error) {
+	// Unlike time.After, the timer is stopped on the fast path and can be collected
+	// immediately instead of living on for the full timeout.
+	timer := clk.NewTimer(FetchTimeout)
+	defer timer.Stop()
 
 	select {
 	case resp := <-respCh:
-		// The 30s timer created by time.After still sits in memory!
 		return resp, nil
-	case <-time.After(timeout):
+	case <-timer.C():
 		return "", ErrTimeout
 	}
 }
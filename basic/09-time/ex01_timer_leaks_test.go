@@ -1,7 +1,11 @@
 package timeutil
 
 import (
+	"errors"
 	"testing"
+	"time"
+
+	"go-playbook/basic/09-time/clock"
 )
 
 func TestFetchWithTimeout(t *testing.T) {
@@ -25,3 +29,29 @@ func TestFetchWithTimeout(t *testing.T) {
 	// A strictly black-box unit test cannot easily detect the lack of Stop() without
 	// profiling or injecting time mocks. We leave this as an structural exercise.
 }
+
+func TestFetchWithClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// Fast path: the timer must be released, not left pending for 30 seconds.
+	fastCh := make(chan string, 1)
+	fastCh <- "success"
+	if resp, err := FetchWithClock(clk, fastCh); err != nil || resp != "success" {
+		t.Fatalf("Expected success, got %q, %v", resp, err)
+	}
+	if n := clk.Waiters(); n != 0 {
+		t.Fatalf("Expected the timer to be stopped on the fast path, %d still pending", n)
+	}
+
+	// Slow path: reach the timeout without waiting for it.
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := FetchWithClock(clk, make(chan string))
+		errCh <- err
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(FetchTimeout)
+	if err := <-errCh; !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+}
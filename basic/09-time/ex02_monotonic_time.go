@@ -2,6 +2,8 @@ package timeutil
 
 import (
 	"time"
+
+	"go-playbook/basic/09-time/clock"
 )
 
 // Context: Monotonic Time vs Wall Clock formatting
@@ -20,22 +22,32 @@ import (
 //    has been slightly shifted backwards by NTP.
 // 2. Ensure that durations are positive or zero.
 
+// legacyStartLayout is the zone-less format earlier versions wrote. It is still
+// accepted, interpreted in the local zone it was written in.
+const legacyStartLayout = "2006-01-02 15:04:05"
+
 func FormattedStartTime() (string, time.Time) {
 	now := time.Now()
-	// Formats as: 2006-01-02 15:04:05
-	return now.Format("2006-01-02 15:04:05"), now
+	// RFC 3339 with nanoseconds and offset: the instant round-trips exactly, in any zone.
+	return now.Format(time.RFC3339Nano), now
 }
 
 func Uptime(serializedStart string) time.Duration {
-	// BUG: The serialized date has no monotonic clock.
-	// We parse it back into a time.Time object using only the Wall clock.
-	parsedStart, _ := time.Parse("2006-01-02 15:04:05", serializedStart)
+	return UptimeWithClock(clock.Real, serializedStart)
+}
 
-	// If the server clock jumped backwards by 1 minute between FormattedStartTime
-	// and now, `time.Since` will produce a negative duration!
-	// TODO: Fix this function to at least protect against returning negative durations.
-	// If the duration is negative, return 0 (meaning we can't reliably detect uptime).
+// UptimeWithClock computes Uptime against clk, so tests can move "now" around the
+// serialized start instead of faking the start.
+func UptimeWithClock(clk clock.Clock, serializedStart string) time.Duration {
+	parsedStart, err := time.Parse(time.RFC3339Nano, serializedStart)
+	if err != nil {
+		if parsedStart, err = time.ParseInLocation(legacyStartLayout, serializedStart, time.Local); err != nil {
+			return 0
+		}
+	}
 
-	uptime := time.Since(parsedStart)
-	return uptime
+	// The parsed start has no monotonic reading, so this is wall-clock arithmetic: an
+	// NTP step back can put the start after "now". EstimateUptime clamps that to 0;
+	// persist an UptimeSnapshot to also detect the jump.
+	return EstimateUptime(clk, UptimeSnapshot{Start: parsedStart}, DefaultJumpTolerance).Uptime
 }
@@ -3,6 +3,8 @@ package timeutil
 import (
 	"testing"
 	"time"
+
+	"go-playbook/basic/09-time/clock"
 )
 
 func TestUptimeProtectsAgainstNegative(t *testing.T) {
@@ -20,3 +22,30 @@ func TestUptimeProtectsAgainstNegative(t *testing.T) {
 		t.Fatalf("Expected exactly 0 for negative durations, got %v", duration)
 	}
 }
+
+func TestUptimeWithClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	serialized := start.Format(time.RFC3339Nano)
+	clk := clock.NewFake(start)
+
+	clk.Advance(90 * time.Minute)
+	if got := UptimeWithClock(clk, serialized); got != 90*time.Minute {
+		t.Errorf("Expected 1h30m, got %v", got)
+	}
+
+	stepped := clock.NewFake(start.Add(-time.Minute)) // NTP stepped the clock back.
+	if got := UptimeWithClock(stepped, serialized); got != 0 {
+		t.Errorf("Expected 0 after the clock moved backwards, got %v", got)
+	}
+}
+
+func TestFormattedStartTimeRoundTrips(t *testing.T) {
+	serialized, start := FormattedStartTime()
+	parsed, err := time.Parse(time.RFC3339Nano, serialized)
+	if err != nil || !parsed.Equal(start) {
+		t.Fatalf("Expected %q to parse back to %v, got %v (%v)", serialized, start, parsed, err)
+	}
+	if Uptime("not a time") != 0 {
+		t.Errorf("Expected 0 for an unparseable start")
+	}
+}
@@ -0,0 +1,119 @@
+package timeutil
+
+import (
+	"time"
+
+	"go-playbook/basic/09-time/clock"
+)
+
+// Context: Uptime That Survives Serialization
+// The status page shows how long each service has been up. The service writes its
+// start time to disk, and the page subtracts it from "now". After an NTP correction
+// or a VM resume, the page shows negative uptimes, or days that never happened.
+//
+// Why this matters: Inside the process, the monotonic clock measures uptime exactly,
+// whatever the wall clock does. Once the start time is serialized, only the wall
+// reading survives, and the wall clock can jump. The fix is to persist enough to
+// notice the jumps:
+// 1. `UptimeTracker` measures uptime in-process from the monotonic reading.
+// 2. `UptimeSnapshot` persists the start and the last-seen wall times, formatted as
+//    RFC 3339 with nanoseconds and the UTC offset, plus the monotonic uptime at
+//    last-seen. A wall clock that ran true satisfies Start + Uptime == Seen.
+// 3. `EstimateUptime` reads a snapshot, possibly from a previous process. It reports
+//    the wall-clock jumps it can prove and never returns a negative uptime.
+
+// DefaultJumpTolerance absorbs scheduling noise and slewing when comparing wall and
+// monotonic elapsed time.
+const DefaultJumpTolerance = time.Second
+
+// UptimeTracker measures how long something has been running.
+type UptimeTracker struct {
+	clock clock.Clock
+	start time.Time // Carries the monotonic reading when clock is clock.Real
+}
+
+// NewUptimeTracker starts tracking from clk.Now().
+func NewUptimeTracker(clk clock.Clock) *UptimeTracker {
+	return &UptimeTracker{clock: clk, start: clk.Now()}
+}
+
+// processUptime starts when the package is initialized, at process start.
+var processUptime = NewUptimeTracker(clock.Real)
+
+// ProcessUptime returns how long the process has been running, from the monotonic
+// clock: wall-clock jumps do not affect it.
+func ProcessUptime() time.Duration { return processUptime.Uptime() }
+
+// ProcessSnapshot returns the process uptime in a form that can be persisted.
+func ProcessSnapshot() UptimeSnapshot { return processUptime.Snapshot() }
+
+// Start returns the wall-clock start time.
+func (u *UptimeTracker) Start() time.Time { return u.start.Round(0) }
+
+// Uptime returns the monotonic time elapsed since the start.
+func (u *UptimeTracker) Uptime() time.Duration { return u.clock.Since(u.start) }
+
+// Snapshot records the start, the current wall time and the uptime measured
+// between them.
+func (u *UptimeTracker) Snapshot() UptimeSnapshot {
+	now := u.clock.Now()
+	return UptimeSnapshot{
+		Start:  u.start.Round(0),
+		Seen:   now.Round(0),
+		Uptime: now.Sub(u.start), // Monotonic when both readings have one
+	}
+}
+
+// UptimeSnapshot is the persisted form of an uptime. time.Time marshals to JSON as
+// RFC 3339 with nanoseconds and offset, so both instants round-trip exactly.
+type UptimeSnapshot struct {
+	Start  time.Time     `json:"start"`
+	Seen   time.Time     `json:"seen"`
+	Uptime time.Duration `json:"uptime_ns"`
+}
+
+// ClockJump is a wall-clock step detected by comparing wall and monotonic time.
+// A positive Offset means the wall clock jumped forward.
+type ClockJump struct {
+	Offset time.Duration
+	During string // "run" between Start and Seen; "restart" between Seen and now
+}
+
+// UptimeEstimate is the result of reading an uptime back from a snapshot.
+type UptimeEstimate struct {
+	Uptime time.Duration // Best estimate; never negative
+	Jumps  []ClockJump
+}
+
+// Reliable reports whether the estimate is free of detected jumps.
+func (e UptimeEstimate) Reliable() bool { return len(e.Jumps) == 0 }
+
+// EstimateUptime computes the uptime described by snap as of clk.Now().
+//
+// The monotonic Uptime is trusted up to Seen. After Seen, only the wall clock
+// remains, so the time since Seen is added unless it is negative, which proves a
+// backwards jump. A snapshot without Seen (only a start time) falls back to the
+// wall-clock difference, clamped at zero.
+func EstimateUptime(clk clock.Clock, snap UptimeSnapshot, tolerance time.Duration) UptimeEstimate {
+	now := clk.Now().Round(0)
+
+	if snap.Seen.IsZero() {
+		elapsed := now.Sub(snap.Start)
+		if elapsed < 0 {
+			return UptimeEstimate{Jumps: []ClockJump{{Offset: elapsed, During: "restart"}}}
+		}
+		return UptimeEstimate{Uptime: elapsed}
+	}
+
+	var est UptimeEstimate
+	if drift := snap.Seen.Sub(snap.Start) - snap.Uptime; drift > tolerance || drift < -tolerance {
+		est.Jumps = append(est.Jumps, ClockJump{Offset: drift, During: "run"})
+	}
+
+	sinceSeen := now.Sub(snap.Seen)
+	if sinceSeen < -tolerance {
+		est.Jumps = append(est.Jumps, ClockJump{Offset: sinceSeen, During: "restart"})
+	}
+	est.Uptime = snap.Uptime + max(sinceSeen, 0)
+	return est
+}
@@ -0,0 +1,102 @@
+package timeutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go-playbook/basic/09-time/clock"
+)
+
+func TestUptimeTracker(t *testing.T) {
+	start := time.Date(2024, 3, 10, 8, 0, 0, 123456789, time.FixedZone("BRT", -3*3600))
+	clk := clock.NewFake(start)
+	tracker := NewUptimeTracker(clk)
+
+	clk.Advance(2 * time.Hour)
+	if got := tracker.Uptime(); got != 2*time.Hour {
+		t.Errorf("Expected 2h, got %v", got)
+	}
+
+	snap := tracker.Snapshot()
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"start":"2024-03-10T08:00:00.123456789-03:00"`) {
+		t.Errorf("Expected RFC 3339 with nanoseconds and offset, got %s", data)
+	}
+
+	var back UptimeSnapshot
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !back.Start.Equal(snap.Start) || !back.Seen.Equal(snap.Seen) || back.Uptime != snap.Uptime {
+		t.Errorf("Expected %+v to round-trip, got %+v", snap, back)
+	}
+	if _, offset := back.Start.Zone(); offset != -3*3600 {
+		t.Errorf("Expected the offset to survive, got %d", offset)
+	}
+}
+
+func TestProcessUptimeIsMonotonic(t *testing.T) {
+	a := ProcessUptime()
+	b := ProcessUptime()
+	if a < 0 || b < a {
+		t.Errorf("Expected a non-decreasing uptime, got %v then %v", a, b)
+	}
+	if snap := ProcessSnapshot(); snap.Seen.Before(snap.Start) {
+		t.Errorf("Expected Seen after Start, got %+v", snap)
+	}
+}
+
+func TestEstimateUptimeDetectsJumps(t *testing.T) {
+	start := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+	clean := UptimeSnapshot{Start: start, Seen: start.Add(time.Hour), Uptime: time.Hour}
+
+	tests := []struct {
+		name     string
+		snap     UptimeSnapshot
+		now      time.Time
+		want     time.Duration
+		wantJump []ClockJump
+	}{
+		{"clean restart", clean, start.Add(90 * time.Minute), 90 * time.Minute, nil},
+		{"jitter within tolerance", UptimeSnapshot{Start: start, Seen: start.Add(time.Hour + 300*time.Millisecond), Uptime: time.Hour}, start.Add(time.Hour + 300*time.Millisecond), time.Hour, nil},
+		{
+			"NTP stepped forward during the run",
+			UptimeSnapshot{Start: start, Seen: start.Add(65 * time.Minute), Uptime: time.Hour},
+			start.Add(65 * time.Minute),
+			time.Hour,
+			[]ClockJump{{Offset: 5 * time.Minute, During: "run"}},
+		},
+		{
+			"clock moved back across the restart",
+			clean,
+			start.Add(58 * time.Minute),
+			time.Hour,
+			[]ClockJump{{Offset: -2 * time.Minute, During: "restart"}},
+		},
+		{"start only", UptimeSnapshot{Start: start}, start.Add(time.Hour), time.Hour, nil},
+		{
+			"start only, in the future",
+			UptimeSnapshot{Start: start},
+			start.Add(-time.Minute),
+			0,
+			[]ClockJump{{Offset: -time.Minute, During: "restart"}},
+		},
+	}
+	for _, tt := range tests {
+		est := EstimateUptime(clock.NewFake(tt.now), tt.snap, DefaultJumpTolerance)
+		if est.Uptime != tt.want {
+			t.Errorf("%s: Expected uptime %v, got %v", tt.name, tt.want, est.Uptime)
+		}
+		if len(est.Jumps) != len(tt.wantJump) || (len(est.Jumps) > 0 && est.Jumps[0] != tt.wantJump[0]) {
+			t.Errorf("%s: Expected jumps %+v, got %+v", tt.name, tt.wantJump, est.Jumps)
+		}
+		if est.Reliable() != (len(tt.wantJump) == 0) {
+			t.Errorf("%s: Reliable() disagrees with the detected jumps", tt.name)
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package composition
 
+import (
+	"context"
+
+	"go-playbook/intermediate/10-methods-and-composition/notify"
+)
+
 // Title: Interface-Driven Design & Minimal Contracts
 //
 // Context: You are reviewing a PR for a new User Onboarding service.
@@ -35,17 +41,28 @@ func (c *ThirdPartyEmailClientImpl) PingServer() error {
 
 // -----------------------------
 
-// TODO: Define the Consumer Interface `EmailSender` here.
-// It should only include the methods `OnboardUser` actually uses!
+// The consumer interface is notify.Sender: one method, and the vendor client never
+// appears in business code. VendorEmailSender adapts the client to it; tests pass a
+// notify.Recorder or any other fake.
 
-// BUG: Tightly coupled to a concrete implementation.
-// TODO: Refactor `client` to use your new interface.
-func OnboardUser(client *ThirdPartyEmailClientImpl, email string) error {
+// VendorEmailSender adapts ThirdPartyEmailClientImpl to notify.Sender.
+type VendorEmailSender struct {
+	Client *ThirdPartyEmailClientImpl
+}
 
-	err := client.SendEmail(email, "Welcome!", "Thanks for signing up.")
-	if err != nil {
+func (s VendorEmailSender) Send(ctx context.Context, msg notify.Message) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
+	return s.Client.SendEmail(msg.To, msg.Subject, msg.Body)
+}
 
-	return nil
+// OnboardUser sends the welcome message through whichever transport it is given.
+func OnboardUser(sender notify.Sender, email string) error {
+	return OnboardUserContext(context.Background(), sender, email)
+}
+
+// OnboardUserContext is OnboardUser with a caller-supplied context.
+func OnboardUserContext(ctx context.Context, sender notify.Sender, email string) error {
+	return sender.Send(ctx, notify.Message{To: email, Subject: "Welcome!", Body: "Thanks for signing up."})
 }
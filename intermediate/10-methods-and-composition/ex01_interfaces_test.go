@@ -1,7 +1,11 @@
 package composition
 
 import (
+	"context"
+	"errors"
 	"testing"
+
+	"go-playbook/intermediate/10-methods-and-composition/notify"
 )
 
 // MockClient is used to test OnboardUser without hitting real APIs.
@@ -9,9 +13,14 @@ type MockClient struct {
 	SentTo string
 }
 
-// TODO: Ensure MockClient satisfies the EmailSender interface you defined in ex01_interfaces.go.
-// Implement the required method(s) here.
-// Simulate a failure if the email is "fail@test.com", otherwise record the email and return nil.
+// Send satisfies notify.Sender: it fails for "fail@test.com" and records everything else.
+func (m *MockClient) Send(ctx context.Context, msg notify.Message) error {
+	if msg.To == "fail@test.com" {
+		return errors.New("mock: delivery failed")
+	}
+	m.SentTo = msg.To
+	return nil
+}
 
 func TestOnboardUser(t *testing.T) {
 	mock := &MockClient{}
@@ -34,3 +43,18 @@ func TestOnboardUser(t *testing.T) {
 		t.Fatal("Expected an error for the failure scenario, got nil")
 	}
 }
+
+func TestOnboardUserThroughVendorClient(t *testing.T) {
+	var _ notify.Sender = VendorEmailSender{}
+
+	sender := VendorEmailSender{Client: &ThirdPartyEmailClientImpl{APIKey: "k"}}
+	if err := OnboardUser(sender, "new@user.com"); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := OnboardUserContext(ctx, sender, "new@user.com"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
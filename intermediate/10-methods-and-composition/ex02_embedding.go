@@ -20,29 +20,24 @@ package composition
 // 3. Fix the instantiation in `NewCounter` so it doesn't panic on a nil pointer dereference
 //    when trying to access the embedded `*BaseMetric`.
 
-import "time"
+import "go-playbook/intermediate/10-methods-and-composition/metrics"
 
-type BaseMetric struct {
-	ID        string
-	LastEvent time.Time
-}
-
-func (b *BaseMetric) Record() {
-	b.LastEvent = time.Now()
-}
+// BaseMetric (ID, LastEvent and Record) lives in the metrics package, where the
+// registry's Counter, Gauge and Histogram embed it too: one base for every metric.
 
 type CounterMetric struct {
-	*BaseMetric // Embedded pointer. Promotes all *BaseMetric methods.
-	Count       int
+	*metrics.BaseMetric // Embedded pointer. Promotes all *BaseMetric fields and methods.
+	Count               int
 }
 
-// TODO: Define the `Record()` method for `*CounterMetric`.
-// It should increment `Count`, and then call the underlying `BaseMetric.Record()`.
+// Record shadows the promoted BaseMetric.Record and calls it explicitly.
+func (c *CounterMetric) Record() {
+	c.Count++
+	c.BaseMetric.Record()
+}
 
 // NewCounter acts as a constructor.
 func NewCounter(id string) *CounterMetric {
-	// BUG: If we just return `&CounterMetric{}` leaving the embedded pointer nil,
-	// calling `Record()` promoted from `BaseMetric` will panic!
-	// TODO: Properly initialize the embedded struct.
-	return &CounterMetric{}
+	// The embedded pointer must be set, or every promoted method dereferences nil.
+	return &CounterMetric{BaseMetric: &metrics.BaseMetric{ID: id}}
 }
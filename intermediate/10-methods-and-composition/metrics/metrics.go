@@ -0,0 +1,321 @@
+// Package metrics is a small metrics registry with Prometheus text exposition.
+//
+// Counters, gauges and histograms all embed BaseMetric, which carries what every metric
+// shares: its name, help text, label names and creation and last-update timestamps.
+// Each metric is a family of series, one per combination of label values:
+//
+//	requests := metrics.NewCounter(metrics.Opts{
+//		Name:   "http_requests_total",
+//		Help:   "HTTP requests served.",
+//		Labels: []string{"method", "code"},
+//	})
+//	reg := metrics.NewRegistry()
+//	reg.MustRegister(requests)
+//	http.Handle("/metrics", reg)
+//
+//	requests.With("GET", "200").Inc()
+//
+// All metric methods are safe for concurrent use.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-playbook/basic/09-time/clock"
+)
+
+// DefBuckets suit request latencies measured in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Opts names and describes a metric.
+type Opts struct {
+	Name   string
+	Help   string
+	Labels []string    // Label names; each series sets one value per name
+	Clock  clock.Clock // Default clock.Real; used for the timestamps on BaseMetric
+}
+
+// BaseMetric is the identity and bookkeeping shared by every metric type. Counter,
+// Gauge and Histogram embed it, and so does CounterMetric in ex02_embedding.go, so its
+// fields and Record are promoted to all of them. The identity fields are set when the
+// metric is created and must not change once it is registered.
+type BaseMetric struct {
+	ID      string   // Metric name, e.g. "http_requests_total"
+	Help    string   // Optional description for # HELP
+	Labels  []string // Label names; each series sets one value per name
+	Created time.Time
+
+	// LastEvent is set by Record, for single-goroutine embedders such as CounterMetric.
+	// Series updates skip it to stay lock-free; LastUpdate sees both.
+	LastEvent time.Time
+
+	lastNanos atomic.Int64 // Unix nanoseconds of the last update; 0 if none
+	clk       clock.Clock  // nil means the system clock
+}
+
+func (b *BaseMetric) init(opts Opts) {
+	b.ID, b.Help, b.Labels = opts.Name, opts.Help, slices.Clone(opts.Labels)
+	b.clk = opts.Clock
+	b.Created = b.now()
+}
+
+func (b *BaseMetric) now() time.Time {
+	if b.clk == nil {
+		return time.Now()
+	}
+	return b.clk.Now()
+}
+
+// Name returns ID; it lets the Registry treat every metric type alike.
+func (b *BaseMetric) Name() string { return b.ID }
+
+// Record stamps LastEvent and the last update. It writes LastEvent without
+// synchronization, so it is not for metrics shared between goroutines.
+func (b *BaseMetric) Record() {
+	now := b.now()
+	b.LastEvent = now
+	b.lastNanos.Store(now.UnixNano())
+}
+
+// touch stamps the last update. Series call it on every change, so it is a single
+// atomic store: a mutex here would serialize every series of a hot metric.
+func (b *BaseMetric) touch() {
+	b.lastNanos.Store(b.now().UnixNano())
+}
+
+// LastUpdate returns when the metric last changed, or the zero time if it never did.
+// It is safe while other goroutines update the metric.
+func (b *BaseMetric) LastUpdate() time.Time {
+	n := b.lastNanos.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// family stores the series of one metric, keyed by their label values.
+type family[S any] struct {
+	mu     sync.RWMutex
+	series map[string]*labeled[S]
+}
+
+type labeled[S any] struct {
+	values []string
+	s      *S
+}
+
+// get returns the series for values, creating it on first use. A wrong number of
+// values is a programming error, so it panics like an out-of-range index.
+func (f *family[S]) get(b *BaseMetric, values []string, init func() *S) *S {
+	if len(values) != len(b.Labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", b.ID, len(b.Labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.RLock()
+	l, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return l.s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if l, ok := f.series[key]; ok {
+		return l.s
+	}
+	if f.series == nil {
+		f.series = map[string]*labeled[S]{}
+	}
+	l = &labeled[S]{values: slices.Clone(values), s: init()}
+	f.series[key] = l
+	return l.s
+}
+
+// sorted returns the series ordered by label values, for stable output.
+func (f *family[S]) sorted() []*labeled[S] {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]*labeled[S], 0, len(f.series))
+	for _, l := range f.series {
+		out = append(out, l)
+	}
+	slices.SortFunc(out, func(a, b *labeled[S]) int { return slices.Compare(a.values, b.values) })
+	return out
+}
+
+// atomicFloat is a float64 updated with compare-and-swap.
+type atomicFloat struct{ bits atomic.Uint64 }
+
+func (f *atomicFloat) Load() float64   { return math.Float64frombits(f.bits.Load()) }
+func (f *atomicFloat) Store(v float64) { f.bits.Store(math.Float64bits(v)) }
+
+func (f *atomicFloat) Add(delta float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// Counter is a value that only goes up: requests served, errors, bytes sent.
+type Counter struct {
+	BaseMetric
+	family family[CounterSeries]
+}
+
+// CounterSeries is one labeled series of a Counter.
+type CounterSeries struct {
+	base  *BaseMetric
+	value atomicFloat
+}
+
+func NewCounter(opts Opts) *Counter {
+	c := &Counter{}
+	c.init(opts)
+	return c
+}
+
+// With returns the series for the given label values, in Opts.Labels order.
+func (c *Counter) With(values ...string) *CounterSeries {
+	return c.family.get(&c.BaseMetric, values, func() *CounterSeries { return &CounterSeries{base: &c.BaseMetric} })
+}
+
+// Inc and Add update the unlabeled series.
+func (c *Counter) Inc()          { c.With().Inc() }
+func (c *Counter) Add(v float64) { c.With().Add(v) }
+
+func (s *CounterSeries) Inc() { s.Add(1) }
+
+// Add increases the counter. A negative or NaN v panics: a counter that goes down
+// breaks every rate computed from it, and one NaN would stick forever.
+func (s *CounterSeries) Add(v float64) {
+	if v < 0 || math.IsNaN(v) {
+		panic(fmt.Sprintf("metrics: counter %s cannot add %v", s.base.ID, v))
+	}
+	s.value.Add(v)
+	s.base.touch()
+}
+
+func (s *CounterSeries) Value() float64 { return s.value.Load() }
+
+// Gauge is a value that goes up and down: queue depth, open connections, temperature.
+type Gauge struct {
+	BaseMetric
+	family family[GaugeSeries]
+}
+
+// GaugeSeries is one labeled series of a Gauge.
+type GaugeSeries struct {
+	base  *BaseMetric
+	value atomicFloat
+}
+
+func NewGauge(opts Opts) *Gauge {
+	g := &Gauge{}
+	g.init(opts)
+	return g
+}
+
+// With returns the series for the given label values, in Opts.Labels order.
+func (g *Gauge) With(values ...string) *GaugeSeries {
+	return g.family.get(&g.BaseMetric, values, func() *GaugeSeries { return &GaugeSeries{base: &g.BaseMetric} })
+}
+
+// Set, Inc, Dec and Add update the unlabeled series.
+func (g *Gauge) Set(v float64) { g.With().Set(v) }
+func (g *Gauge) Inc()          { g.With().Inc() }
+func (g *Gauge) Dec()          { g.With().Dec() }
+func (g *Gauge) Add(v float64) { g.With().Add(v) }
+
+func (s *GaugeSeries) Set(v float64) {
+	s.value.Store(v)
+	s.base.touch()
+}
+
+func (s *GaugeSeries) Add(v float64) {
+	s.value.Add(v)
+	s.base.touch()
+}
+
+func (s *GaugeSeries) Inc()           { s.Add(1) }
+func (s *GaugeSeries) Dec()           { s.Add(-1) }
+func (s *GaugeSeries) Value() float64 { return s.value.Load() }
+
+// Histogram counts observations into fixed buckets: latencies, payload sizes.
+type Histogram struct {
+	BaseMetric
+	buckets []float64 // Upper bounds, strictly increasing; +Inf is implicit
+	family  family[HistogramSeries]
+}
+
+// HistogramSeries is one labeled series of a Histogram.
+type HistogramSeries struct {
+	base    *BaseMetric
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64 // Per bucket, not cumulative; the last one is +Inf
+	sum    float64
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds, or
+// DefBuckets if none are given. Invalid buckets are reported by Registry.Register.
+func NewHistogram(opts Opts, buckets ...float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	h := &Histogram{buckets: slices.Clone(buckets)}
+	h.init(opts)
+	return h
+}
+
+// Buckets returns the bucket upper bounds, without the implicit +Inf.
+func (h *Histogram) Buckets() []float64 { return slices.Clone(h.buckets) }
+
+// With returns the series for the given label values, in Opts.Labels order.
+func (h *Histogram) With(values ...string) *HistogramSeries {
+	return h.family.get(&h.BaseMetric, values, func() *HistogramSeries {
+		return &HistogramSeries{base: &h.BaseMetric, buckets: h.buckets, counts: make([]uint64, len(h.buckets)+1)}
+	})
+}
+
+// Observe records v in the unlabeled series.
+func (h *Histogram) Observe(v float64) { h.With().Observe(v) }
+
+// Observe records v in the first bucket whose upper bound is >= v. A NaN v panics,
+// as it does for a counter: it has no bucket and would turn the sum into NaN for good.
+func (s *HistogramSeries) Observe(v float64) {
+	if math.IsNaN(v) {
+		panic(fmt.Sprintf("metrics: histogram %s cannot observe NaN", s.base.ID))
+	}
+	i := sort.SearchFloat64s(s.buckets, v)
+
+	s.mu.Lock()
+	s.counts[i]++
+	s.sum += v
+	s.mu.Unlock()
+	s.base.touch()
+}
+
+// Snapshot returns cumulative bucket counts (the last is +Inf, equal to the total
+// count) and the sum of all observations, read consistently.
+func (s *HistogramSeries) Snapshot() (cumulative []uint64, sum float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cumulative = make([]uint64, len(s.counts))
+	var total uint64
+	for i, n := range s.counts {
+		total += n
+		cumulative[i] = total
+	}
+	return cumulative, s.sum
+}
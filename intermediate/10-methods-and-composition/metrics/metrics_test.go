@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go-playbook/basic/09-time/clock"
+)
+
+func TestBaseTimestamps(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	c := NewCounter(Opts{Name: "jobs_total", Help: "Jobs run.", Labels: []string{"queue"}, Clock: clk})
+
+	if !c.Created.Equal(start) || !c.LastUpdate().IsZero() {
+		t.Errorf("Expected created %v and no update yet, got %v and %v", start, c.Created, c.LastUpdate())
+	}
+
+	clk.Advance(time.Minute)
+	c.With("emails").Inc()
+	if want := start.Add(time.Minute); !c.LastUpdate().Equal(want) {
+		t.Errorf("Expected last update %v, got %v", want, c.LastUpdate())
+	}
+	if c.ID != "jobs_total" || !slices.Equal(c.Labels, []string{"queue"}) {
+		t.Errorf("Expected the identity to be promoted from BaseMetric, got %q %v", c.ID, c.Labels)
+	}
+}
+
+func TestCounterAndGauge(t *testing.T) {
+	c := NewCounter(Opts{Name: "requests_total", Labels: []string{"code"}})
+	g := NewGauge(Opts{Name: "in_flight"})
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			g.Inc()
+			c.With("200").Inc()
+			c.With("500").Add(0.5)
+			g.Dec()
+		})
+	}
+	wg.Wait()
+
+	if got := c.With("200").Value(); got != 50 {
+		t.Errorf("Expected 50, got %v", got)
+	}
+	if got := c.With("500").Value(); got != 25 {
+		t.Errorf("Expected 25, got %v", got)
+	}
+	if got := g.With().Value(); got != 0 {
+		t.Errorf("Expected the gauge back at 0, got %v", got)
+	}
+	g.Set(-3.5)
+	if got := g.With().Value(); got != -3.5 {
+		t.Errorf("Expected -3.5, got %v", got)
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	h := NewHistogram(Opts{Name: "latency_seconds"}, 0.1, 0.5, 1)
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.7, 2, 9} {
+		h.Observe(v)
+	}
+
+	cumulative, sum := h.With().Snapshot()
+	if want := []uint64{2, 3, 4, 6}; !slices.Equal(cumulative, want) {
+		t.Errorf("Expected cumulative counts %v (le is inclusive), got %v", want, cumulative)
+	}
+	if sum != 12.15 {
+		t.Errorf("Expected sum 12.15, got %v", sum)
+	}
+}
+
+func TestMisuse(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"missing label value", func() { NewCounter(Opts{Name: "x", Labels: []string{"a"}}).Inc() }},
+		{"extra label value", func() { NewGauge(Opts{Name: "x"}).With("a") }},
+		{"negative counter", func() { NewCounter(Opts{Name: "x"}).Add(-1) }},
+		{"NaN counter", func() { NewCounter(Opts{Name: "x"}).Add(math.NaN()) }},
+		{"NaN observation", func() { NewHistogram(Opts{Name: "x"}).Observe(math.NaN()) }},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: Expected a panic", tt.name)
+				}
+			}()
+			tt.fn()
+		}()
+	}
+}
@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrDuplicateMetric = errors.New("metrics: metric already registered")
+	ErrInvalidName     = errors.New("metrics: invalid metric or label name")
+	ErrInvalidBuckets  = errors.New("metrics: histogram buckets must be strictly increasing")
+)
+
+// ContentType is the media type of the Prometheus text exposition format 0.0.4.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	metricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelName  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Metric is implemented by *Counter, *Gauge and *Histogram.
+type Metric interface {
+	Name() string
+	writeText(w *bufio.Writer)
+	validate() error
+}
+
+// Registry holds metrics by name and serves them in the Prometheus text format.
+// It implements http.Handler, so it can be mounted directly at /metrics.
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]Metric
+}
+
+func NewRegistry() *Registry { return &Registry{metrics: map[string]Metric{}} }
+
+// Register adds metrics. It fails without registering any of them if one has an
+// invalid name, label or bucket layout, or shares a name with a registered metric
+// or with another in the same call.
+func (r *Registry) Register(metrics ...Metric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := map[string]bool{}
+	for _, m := range metrics {
+		if err := m.validate(); err != nil {
+			return err
+		}
+		if _, ok := r.metrics[m.Name()]; ok || seen[m.Name()] {
+			return fmt.Errorf("%w: %q", ErrDuplicateMetric, m.Name())
+		}
+		seen[m.Name()] = true
+	}
+	for _, m := range metrics {
+		r.metrics[m.Name()] = m
+	}
+	return nil
+}
+
+// MustRegister is Register for package-level setup, where a failure is a bug.
+func (r *Registry) MustRegister(metrics ...Metric) {
+	if err := r.Register(metrics...); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the named metric and reports whether it was registered.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.metrics[name]
+	delete(r.metrics, name)
+	return ok
+}
+
+// WriteText writes every metric in the text exposition format, sorted by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	names := slices.Sorted(maps.Keys(r.metrics))
+	metrics := make([]Metric, len(names))
+	for i, name := range names {
+		metrics[i] = r.metrics[name]
+	}
+	r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.writeText(bw)
+	}
+	return bw.Flush()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	if req.Method == http.MethodHead {
+		return
+	}
+	r.WriteText(w) // A failed write means the scraper went away; nothing to report to.
+}
+
+func (b *BaseMetric) validate() error {
+	if !metricName.MatchString(b.ID) {
+		return fmt.Errorf("%w: metric %q", ErrInvalidName, b.ID)
+	}
+	for i, l := range b.Labels {
+		if !labelName.MatchString(l) || strings.HasPrefix(l, "__") {
+			return fmt.Errorf("%w: label %q of %s", ErrInvalidName, l, b.ID)
+		}
+		if slices.Contains(b.Labels[:i], l) {
+			return fmt.Errorf("%w: label %q repeated in %s", ErrInvalidName, l, b.ID)
+		}
+	}
+	return nil
+}
+
+func (h *Histogram) validate() error {
+	if err := h.BaseMetric.validate(); err != nil {
+		return err
+	}
+	if slices.Contains(h.Labels, "le") {
+		return fmt.Errorf("%w: label \"le\" is reserved in histogram %s", ErrInvalidName, h.ID)
+	}
+	for i, upper := range h.buckets {
+		if math.IsNaN(upper) || math.IsInf(upper, 1) || (i > 0 && upper <= h.buckets[i-1]) {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBuckets, h.ID, h.buckets)
+		}
+	}
+	return nil
+}
+
+// writeHeader writes the # HELP and # TYPE lines of a family.
+func (b *BaseMetric) writeHeader(w *bufio.Writer, typ string) {
+	if b.Help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", b.ID, helpEscaper.Replace(b.Help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", b.ID, typ)
+}
+
+// writeSample writes one sample line. extra is an additional label pair, such as a
+// histogram's le, appended after the metric's own labels.
+func (b *BaseMetric) writeSample(w *bufio.Writer, suffix string, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(b.ID)
+	w.WriteString(suffix)
+	if len(values) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, value := range values {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, b.Labels[i], value)
+		}
+		if extraName != "" {
+			if len(values) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	w.WriteString(name)
+	w.WriteString(`="`)
+	labelEscaper.WriteString(w, value)
+	w.WriteByte('"')
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// formatFloat renders v as the format expects: shortest round-trip representation,
+// with +Inf, -Inf and NaN spelled out.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (c *Counter) writeText(w *bufio.Writer) {
+	c.writeHeader(w, "counter")
+	for _, l := range c.family.sorted() {
+		c.writeSample(w, "", l.values, "", "", l.s.Value())
+	}
+}
+
+func (g *Gauge) writeText(w *bufio.Writer) {
+	g.writeHeader(w, "gauge")
+	for _, l := range g.family.sorted() {
+		g.writeSample(w, "", l.values, "", "", l.s.Value())
+	}
+}
+
+func (h *Histogram) writeText(w *bufio.Writer) {
+	h.writeHeader(w, "histogram")
+	for _, l := range h.family.sorted() {
+		cumulative, sum := l.s.Snapshot()
+		for i, upper := range h.buckets {
+			h.writeSample(w, "_bucket", l.values, "le", formatFloat(upper), float64(cumulative[i]))
+		}
+		count := cumulative[len(cumulative)-1]
+		h.writeSample(w, "_bucket", l.values, "le", "+Inf", float64(count))
+		h.writeSample(w, "_sum", l.values, "", "", sum)
+		h.writeSample(w, "_count", l.values, "", "", float64(count))
+	}
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegisterRejects(t *testing.T) {
+	reg := NewRegistry()
+	reg.MustRegister(NewCounter(Opts{Name: "requests_total"}))
+
+	tests := []struct {
+		name   string
+		metric Metric
+		want   error
+	}{
+		{"duplicate", NewGauge(Opts{Name: "requests_total"}), ErrDuplicateMetric},
+		{"bad metric name", NewCounter(Opts{Name: "2xx-requests"}), ErrInvalidName},
+		{"bad label", NewCounter(Opts{Name: "a", Labels: []string{"http-code"}}), ErrInvalidName},
+		{"reserved label", NewCounter(Opts{Name: "b", Labels: []string{"__name"}}), ErrInvalidName},
+		{"repeated label", NewCounter(Opts{Name: "c", Labels: []string{"x", "x"}}), ErrInvalidName},
+		{"le on a histogram", NewHistogram(Opts{Name: "d", Labels: []string{"le"}}), ErrInvalidName},
+		{"unsorted buckets", NewHistogram(Opts{Name: "e"}, 1, 0.5), ErrInvalidBuckets},
+		{"infinite bucket", NewHistogram(Opts{Name: "f"}, 1, math.Inf(1)), ErrInvalidBuckets},
+	}
+	for _, tt := range tests {
+		if err := reg.Register(tt.metric); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// One bad metric keeps the whole batch out.
+	if err := reg.Register(NewGauge(Opts{Name: "ok"}), NewGauge(Opts{Name: "ok"})); !errors.Is(err, ErrDuplicateMetric) {
+		t.Errorf("Expected ErrDuplicateMetric within the batch, got %v", err)
+	}
+	if reg.Unregister("ok") {
+		t.Error("Expected nothing from the failed batch to be registered")
+	}
+	if !reg.Unregister("requests_total") || reg.Register(NewGauge(Opts{Name: "requests_total"})) != nil {
+		t.Error("Expected the name to be free again after Unregister")
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	requests := NewCounter(Opts{Name: "http_requests_total", Help: "Requests served.", Labels: []string{"method", "path"}})
+	temp := NewGauge(Opts{Name: "temperature_celsius", Help: "Line one\nwith a \\ backslash."})
+	latency := NewHistogram(Opts{Name: "latency_seconds", Labels: []string{"op"}}, 0.25, 1)
+
+	reg := NewRegistry()
+	reg.MustRegister(requests, temp, latency)
+
+	requests.With("GET", "/").Add(3)
+	requests.With("GET", `/q"uote`).Inc()
+	temp.Set(-1.5)
+	latency.With("read").Observe(0.125)
+	latency.With("read").Observe(3)
+
+	var b strings.Builder
+	if err := reg.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",path="/"} 3
+http_requests_total{method="GET",path="/q\"uote"} 1
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="read",le="0.25"} 1
+latency_seconds_bucket{op="read",le="1"} 1
+latency_seconds_bucket{op="read",le="+Inf"} 2
+latency_seconds_sum{op="read"} 3.125
+latency_seconds_count{op="read"} 2
+# HELP temperature_celsius Line one\nwith a \\ backslash.
+# TYPE temperature_celsius gauge
+temperature_celsius -1.5
+`
+	if got := b.String(); got != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, got)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	reg := NewRegistry()
+	up := NewGauge(Opts{Name: "up"})
+	reg.MustRegister(up)
+	up.Set(1)
+
+	srv := httptest.NewServer(reg)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != ContentType {
+		t.Errorf("Expected %q, got %q", ContentType, ct)
+	}
+	if string(body) != "# TYPE up gauge\nup 1\n" {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	resp, err = http.Post(srv.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,252 @@
+// Package notify delivers notifications through interchangeable transports.
+//
+// Everything is built on one method:
+//
+//	type Sender interface {
+//		Send(ctx context.Context, msg Message) error
+//	}
+//
+// Transports implement it: SMTPSender, WebhookSender and the in-memory Recorder for
+// tests. Policies are Senders that wrap other Senders, so they compose freely:
+// Failover tries its senders in order until one succeeds, and FanOut delivers to all
+// of them concurrently. A Gateway names channels ("email", "chat"), renders
+// Templates into Messages and fans each notification out to the channels it asks for.
+// Business code depends only on Sender or Gateway, never on a vendor's client.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+var (
+	ErrNoSenders        = errors.New("notify: no senders configured")
+	ErrUnknownChannel   = errors.New("notify: unknown channel")
+	ErrDuplicateChannel = errors.New("notify: channel already registered")
+	ErrUnknownTemplate  = errors.New("notify: unknown template")
+)
+
+// Message is one notification to one recipient. To is transport-specific: an email
+// address for SMTP, a user or room ID for a webhook.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Sender delivers a message.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SenderFunc adapts a function to Sender.
+type SenderFunc func(ctx context.Context, msg Message) error
+
+func (f SenderFunc) Send(ctx context.Context, msg Message) error { return f(ctx, msg) }
+
+// Recorder is an in-memory Sender for tests. Set Err to make every Send fail.
+type Recorder struct {
+	mu   sync.Mutex
+	sent []Message
+	Err  error
+}
+
+func (r *Recorder) Send(ctx context.Context, msg Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err
+	}
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of the messages recorded so far.
+func (r *Recorder) Sent() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.sent)
+}
+
+// Failover returns a Sender that tries senders in order and stops at the first
+// success. If all fail, the error joins every attempt.
+func Failover(senders ...Sender) Sender {
+	return SenderFunc(func(ctx context.Context, msg Message) error {
+		if len(senders) == 0 {
+			return ErrNoSenders
+		}
+		var errs []error
+		for i, s := range senders {
+			if err := ctx.Err(); err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+			err := s.Send(ctx, msg)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, fmt.Errorf("attempt %d: %w", i+1, err))
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// FanOut returns a Sender that delivers to every sender concurrently and joins
+// their errors. Like Failover, it returns ErrNoSenders if it has none.
+func FanOut(senders ...Sender) Sender {
+	return SenderFunc(func(ctx context.Context, msg Message) error {
+		if len(senders) == 0 {
+			return ErrNoSenders
+		}
+		errs := make([]error, len(senders))
+		var wg sync.WaitGroup
+		for i, s := range senders {
+			wg.Go(func() { errs[i] = s.Send(ctx, msg) })
+		}
+		wg.Wait()
+		return errors.Join(errs...)
+	})
+}
+
+// ChannelError reports a failed delivery on one Gateway channel.
+type ChannelError struct {
+	Channel string
+	Err     error
+}
+
+func (e *ChannelError) Error() string { return fmt.Sprintf("channel %q: %v", e.Channel, e.Err) }
+func (e *ChannelError) Unwrap() error { return e.Err }
+
+// Gateway routes notifications to named channels.
+type Gateway struct {
+	Templates *Templates
+
+	mu       sync.RWMutex
+	channels map[string]Sender
+}
+
+// NewGateway creates a gateway with no channels. templates may be nil.
+func NewGateway(templates *Templates) *Gateway {
+	return &Gateway{Templates: templates, channels: map[string]Sender{}}
+}
+
+// Register adds a channel. Compose its failover order with Failover:
+//
+//	gw.Register("email", notify.Failover(primarySMTP, backupSMTP))
+func (g *Gateway) Register(name string, s Sender) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.channels[name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateChannel, name)
+	}
+	g.channels[name] = s
+	return nil
+}
+
+// Channels returns the registered channel names, sorted.
+func (g *Gateway) Channels() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return slices.Sorted(maps.Keys(g.channels))
+}
+
+// Notify delivers msg to the named channels concurrently, or to every channel if
+// none are named. Failures are *ChannelErrors, joined. A delivery failing on one
+// channel does not affect the others.
+func (g *Gateway) Notify(ctx context.Context, msg Message, channels ...string) error {
+	if len(channels) == 0 {
+		channels = g.Channels()
+	}
+
+	g.mu.RLock()
+	var named []Sender
+	var unknown []error
+	for _, name := range channels {
+		s, ok := g.channels[name]
+		if !ok {
+			unknown = append(unknown, &ChannelError{Channel: name, Err: ErrUnknownChannel})
+			continue
+		}
+		named = append(named, SenderFunc(func(ctx context.Context, msg Message) error {
+			if err := s.Send(ctx, msg); err != nil {
+				return &ChannelError{Channel: name, Err: err}
+			}
+			return nil
+		}))
+	}
+	g.mu.RUnlock()
+
+	if len(unknown) > 0 {
+		return errors.Join(unknown...)
+	}
+	if len(named) == 0 {
+		return ErrNoSenders
+	}
+	return FanOut(named...).Send(ctx, msg)
+}
+
+// NotifyTemplate renders the named template for to and delivers it like Notify.
+func (g *Gateway) NotifyTemplate(ctx context.Context, name, to string, data any, channels ...string) error {
+	if g.Templates == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownTemplate, name)
+	}
+	msg, err := g.Templates.Render(name, to, data)
+	if err != nil {
+		return err
+	}
+	return g.Notify(ctx, msg, channels...)
+}
+
+// Templates holds named subject and body templates (text/template syntax). A
+// reference to a missing key is an error rather than "<no value>".
+type Templates struct {
+	mu sync.RWMutex
+	m  map[string]*template.Template
+}
+
+// NewTemplates returns an empty template set.
+func NewTemplates() *Templates { return &Templates{m: map[string]*template.Template{}} }
+
+// Add parses and registers a template. The subject must render to a single line.
+func (t *Templates) Add(name, subject, body string) error {
+	tmpl := template.New(name).Option("missingkey=error")
+	if _, err := tmpl.New("subject").Parse(subject); err != nil {
+		return fmt.Errorf("notify: template %q subject: %w", name, err)
+	}
+	if _, err := tmpl.New("body").Parse(body); err != nil {
+		return fmt.Errorf("notify: template %q body: %w", name, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.m[name] = tmpl
+	return nil
+}
+
+// Render executes the named template with data into a Message for to.
+func (t *Templates) Render(name, to string, data any) (Message, error) {
+	t.mu.RLock()
+	tmpl, ok := t.m[name]
+	t.mu.RUnlock()
+	if !ok {
+		return Message{}, fmt.Errorf("%w: %q", ErrUnknownTemplate, name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("notify: render %q: %w", name, err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, fmt.Errorf("notify: render %q: %w", name, err)
+	}
+	if strings.ContainsAny(subject.String(), "\r\n") {
+		return Message{}, fmt.Errorf("notify: render %q: subject spans several lines", name)
+	}
+	return Message{To: to, Subject: subject.String(), Body: body.String()}, nil
+}
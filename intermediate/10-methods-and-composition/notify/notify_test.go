@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+var errDown = errors.New("provider down")
+
+// countingSender counts its calls and fails with err.
+type countingSender struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (s *countingSender) Send(ctx context.Context, msg Message) error {
+	s.calls.Add(1)
+	return s.err
+}
+
+func TestFailoverOrder(t *testing.T) {
+	primary := &countingSender{err: errDown}
+	backup := &Recorder{}
+	never := &countingSender{}
+
+	msg := Message{To: "ana@example.com", Subject: "hi"}
+	if err := Failover(primary, backup, never).Send(context.Background(), msg); err != nil {
+		t.Fatalf("Expected the backup to deliver, got %v", err)
+	}
+	if primary.calls.Load() != 1 || len(backup.Sent()) != 1 || never.calls.Load() != 0 {
+		t.Errorf("Expected primary, then backup, and nothing after the first success")
+	}
+
+	second := &countingSender{err: errors.New("quota exceeded")}
+	err := Failover(primary, second).Send(context.Background(), msg)
+	if !errors.Is(err, errDown) || !strings.Contains(err.Error(), "attempt 2: quota exceeded") {
+		t.Errorf("Expected every attempt in the error, got %v", err)
+	}
+	if err := Failover().Send(context.Background(), msg); !errors.Is(err, ErrNoSenders) {
+		t.Errorf("Expected ErrNoSenders, got %v", err)
+	}
+}
+
+func TestFanOutDeliversToAll(t *testing.T) {
+	a, b := &Recorder{}, &Recorder{}
+	failing := &countingSender{err: errDown}
+
+	err := FanOut(a, failing, b).Send(context.Background(), Message{To: "x"})
+	if !errors.Is(err, errDown) {
+		t.Errorf("Expected the failure to be reported, got %v", err)
+	}
+	if len(a.Sent()) != 1 || len(b.Sent()) != 1 {
+		t.Errorf("Expected one failing sender not to stop the others")
+	}
+	if err := FanOut().Send(context.Background(), Message{To: "x"}); !errors.Is(err, ErrNoSenders) {
+		t.Errorf("Expected ErrNoSenders, got %v", err)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	tmpl := NewTemplates()
+	if err := tmpl.Add("receipt", "Receipt #{{.Order}}", "Hi {{.Name}}, you paid {{printf \"%.2f\" .Total}}."); err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpl.Add("broken", "{{.Oops", "body"); err == nil {
+		t.Error("Expected a parse error")
+	}
+
+	msg, err := tmpl.Render("receipt", "ana@example.com", map[string]any{"Order": 42, "Name": "Ana", "Total": 19.5})
+	want := Message{To: "ana@example.com", Subject: "Receipt #42", Body: "Hi Ana, you paid 19.50."}
+	if err != nil || msg != want {
+		t.Errorf("Expected %+v, got %+v (%v)", want, msg, err)
+	}
+
+	if _, err := tmpl.Render("receipt", "x", map[string]any{"Order": 1}); err == nil || !strings.Contains(err.Error(), "Name") {
+		t.Errorf("Expected a missing key to fail, got %v", err)
+	}
+	if _, err := tmpl.Render("nope", "x", nil); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("Expected ErrUnknownTemplate, got %v", err)
+	}
+
+	tmpl.Add("injection", "{{.Subject}}", "")
+	if _, err := tmpl.Render("injection", "x", map[string]string{"Subject": "hi\r\nBcc: everyone@example.com"}); err == nil {
+		t.Error("Expected a multi-line subject to be rejected")
+	}
+}
+
+func TestGatewayRoutesAndFailsOver(t *testing.T) {
+	tmpl := NewTemplates()
+	tmpl.Add("welcome", "Welcome, {{.}}!", "Thanks for signing up, {{.}}.")
+
+	primarySMTP := &countingSender{err: errDown}
+	backupSMTP := &Recorder{}
+	chat := &Recorder{}
+	sms := &Recorder{Err: errors.New("invalid number")}
+
+	gw := NewGateway(tmpl)
+	gw.Register("email", Failover(primarySMTP, backupSMTP))
+	gw.Register("chat", chat)
+	gw.Register("sms", sms)
+	if err := gw.Register("chat", chat); !errors.Is(err, ErrDuplicateChannel) {
+		t.Errorf("Expected ErrDuplicateChannel, got %v", err)
+	}
+
+	if err := gw.NotifyTemplate(context.Background(), "welcome", "ana@example.com", "Ana", "email", "chat"); err != nil {
+		t.Fatalf("NotifyTemplate: %v", err)
+	}
+	want := Message{To: "ana@example.com", Subject: "Welcome, Ana!", Body: "Thanks for signing up, Ana."}
+	if sent := backupSMTP.Sent(); len(sent) != 1 || sent[0] != want {
+		t.Errorf("Expected the email channel to fail over to the backup with %+v, got %+v", want, sent)
+	}
+	if len(chat.Sent()) != 1 || len(sms.Sent()) != 0 {
+		t.Errorf("Expected only the named channels to be used")
+	}
+
+	err := gw.Notify(context.Background(), Message{To: "+5511999999999", Subject: "code 1234"})
+	var chErr *ChannelError
+	if !errors.As(err, &chErr) || chErr.Channel != "sms" {
+		t.Errorf("Expected a ChannelError for sms when notifying every channel, got %v", err)
+	}
+	if !slices.Equal(gw.Channels(), []string{"chat", "email", "sms"}) {
+		t.Errorf("Unexpected channels %v", gw.Channels())
+	}
+
+	if err := gw.Notify(context.Background(), want, "pager"); !errors.Is(err, ErrUnknownChannel) {
+		t.Errorf("Expected ErrUnknownChannel, got %v", err)
+	}
+}
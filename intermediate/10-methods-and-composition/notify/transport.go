@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+)
+
+var ErrWebhookStatus = errors.New("notify: webhook rejected the message")
+
+// SMTPSender delivers messages as plain-text email through an SMTP relay. It speaks
+// the protocol through net/smtp, so in tests it can be pointed at any local server
+// implementing the same handful of commands.
+type SMTPSender struct {
+	Addr string // host:port of the relay
+	From string
+	Auth smtp.Auth // Optional
+
+	// TLSConfig, if set, requires STARTTLS before authenticating or sending.
+	TLSConfig *tls.Config
+}
+
+// Send dials the relay and delivers msg. Cancelling ctx aborts the session.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("notify: smtp dial: %w", err)
+	}
+	// net/smtp has no context support: closing the connection unblocks it instead.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(s.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("notify: smtp greeting: %w", err)
+	}
+	defer c.Close()
+
+	wrap := func(step string, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("notify: smtp %s: %w", step, ctxErr)
+		}
+		return fmt.Errorf("notify: smtp %s: %w", step, err)
+	}
+
+	if s.TLSConfig != nil {
+		if err := c.StartTLS(s.TLSConfig); err != nil {
+			return wrap("starttls", err)
+		}
+	}
+	if s.Auth != nil {
+		if err := c.Auth(s.Auth); err != nil {
+			return wrap("auth", err)
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return wrap("mail from", err)
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return wrap("rcpt to", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return wrap("data", err)
+	}
+	if _, err := w.Write(s.format(msg)); err != nil {
+		return wrap("data", err)
+	}
+	if err := w.Close(); err != nil {
+		return wrap("data", err)
+	}
+	if err := c.Quit(); err != nil {
+		return wrap("quit", err)
+	}
+	return nil
+}
+
+// format renders msg as an RFC 5322 message. net/smtp's data writer takes care of
+// dot-stuffing.
+func (s *SMTPSender) format(msg Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// WebhookSender posts each message as JSON to a URL: chat integrations, paging
+// services, internal notification APIs.
+type WebhookSender struct {
+	URL    string
+	Client *http.Client      // Default http.DefaultClient
+	Header map[string]string // Extra headers, e.g. an authorization token
+}
+
+// Send posts {"to", "subject", "body"} and treats any non-2xx response as a failure.
+func (s *WebhookSender) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("notify: webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.Header {
+		req.Header.Set(k, v)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify: webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%w: %s: %s", ErrWebhookStatus, resp.Status, bytes.TrimSpace(detail))
+	}
+	io.Copy(io.Discard, resp.Body) // Drain, so the connection can be reused.
+	return nil
+}
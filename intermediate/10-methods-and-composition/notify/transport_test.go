@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type receivedMail struct {
+	from, to, auth string
+	data           string
+}
+
+// smtpStandIn is a minimal local SMTP server: enough of RFC 5321 for net/smtp.
+type smtpStandIn struct {
+	addr     string
+	rejectTo string // RCPT TO for this address fails with 550
+
+	mu    sync.Mutex
+	mails []receivedMail
+}
+
+func startSMTP(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &smtpStandIn{addr: ln.Addr().String()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	defer tp.Close()
+
+	var m receivedMail
+	tp.PrintfLine("220 localhost ESMTP stand-in")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			m.auth = strings.ReplaceAll(string(creds), "\x00", "|")
+			tp.PrintfLine("235 2.7.0 Authentication successful")
+		case "MAIL":
+			m.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			m.to = strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if m.to == s.rejectTo {
+				tp.PrintfLine("550 5.1.1 No such user")
+				continue
+			}
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.data = string(data)
+			s.mu.Lock()
+			s.mails = append(s.mails, m)
+			s.mu.Unlock()
+			tp.PrintfLine("250 OK queued")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func (s *smtpStandIn) received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.mails...)
+}
+
+func TestSMTPSenderDelivers(t *testing.T) {
+	server := startSMTP(t)
+	sender := &SMTPSender{
+		Addr: server.addr,
+		From: "noreply@example.com",
+		Auth: smtp.PlainAuth("", "svc", "s3cret", "127.0.0.1"),
+	}
+
+	msg := Message{To: "ana@example.com", Subject: "Olá, Ana", Body: "Line one\n.hidden dot line\nBye"}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	mails := server.received()
+	if len(mails) != 1 {
+		t.Fatalf("Expected 1 mail, got %d", len(mails))
+	}
+	got := mails[0]
+	if got.from != "noreply@example.com" || got.to != "ana@example.com" || got.auth != "|svc|s3cret" {
+		t.Errorf("Unexpected envelope %+v", got)
+	}
+	for _, want := range []string{
+		"To: ana@example.com\n",
+		"Subject: =?utf-8?q?Ol=C3=A1,_Ana?=\n",
+		"Content-Type: text/plain; charset=utf-8\n",
+		"\nLine one\n.hidden dot line\nBye",
+	} {
+		if !strings.Contains(got.data, want) {
+			t.Errorf("Expected %q in the message:\n%s", want, got.data)
+		}
+	}
+}
+
+func TestSMTPSenderReportsRejection(t *testing.T) {
+	server := startSMTP(t)
+	server.rejectTo = "ghost@example.com"
+	sender := &SMTPSender{Addr: server.addr, From: "noreply@example.com"}
+
+	err := sender.Send(context.Background(), Message{To: "ghost@example.com", Subject: "hi"})
+	var tpErr *textproto.Error
+	if !errors.As(err, &tpErr) || tpErr.Code != 550 {
+		t.Errorf("Expected the server's 550, got %v", err)
+	}
+}
+
+func TestSMTPSenderHonorsContext(t *testing.T) {
+	// A relay that accepts connections and never greets.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = (&SMTPSender{Addr: ln.Addr().String(), From: "a@b.c"}).Send(ctx, Message{To: "x@y.z"})
+	if err == nil || time.Since(start) > 2*time.Second {
+		t.Errorf("Expected the hung session to be aborted, got %v after %v", err, time.Since(start))
+	}
+}
+
+func TestWebhookSender(t *testing.T) {
+	var got Message
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if got.To == "blocked" {
+			http.Error(w, "recipient blocked", http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sender := &WebhookSender{URL: srv.URL, Client: srv.Client(), Header: map[string]string{"Authorization": "Bearer t0k"}}
+	msg := Message{To: "#alerts", Subject: "disk", Body: "95% used"}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got != msg || auth != "Bearer t0k" {
+		t.Errorf("Expected %+v with auth, got %+v and %q", msg, got, auth)
+	}
+
+	err := sender.Send(context.Background(), Message{To: "blocked"})
+	if !errors.Is(err, ErrWebhookStatus) || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "recipient blocked") {
+		t.Errorf("Expected ErrWebhookStatus with the status and detail, got %v", err)
+	}
+}
@@ -1,7 +1,11 @@
 package goroutines
 
 import (
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"go-playbook/basic/09-time/clock"
 )
 
 // Context: Goroutine Lifecycle & Leaks
@@ -20,31 +24,61 @@ import (
 // 4. Do not use Context here (we will cover it in Topic 16). Use a `done` channel
 //    or a boolean flag protected by a Mutex (a channel is usually cleaner combined with `select`).
 
+// EvictionInterval is how often the background loop evicts expired items.
+const EvictionInterval = 10 * time.Millisecond
+
 type Cache struct {
-	// TODO: Add fields here to manage the lifecycle (e.g., done channel, WaitGroup)
+	clock     clock.Clock
+	interval  time.Duration
+	done      chan struct{}
+	wg        sync.WaitGroup
+	startOnce sync.Once
+	stopOnce  sync.Once
+	evictions atomic.Int64
 }
 
 func NewCache() *Cache {
-	return &Cache{}
+	return NewCacheWithClock(clock.Real, EvictionInterval)
+}
+
+// NewCacheWithClock creates a cache whose eviction loop ticks on clk, so tests
+// can drive evictions by advancing a fake clock instead of sleeping.
+func NewCacheWithClock(clk clock.Clock, interval time.Duration) *Cache {
+	return &Cache{clock: clk, interval: interval, done: make(chan struct{})}
 }
 
 func (c *Cache) Start() {
-	// BUG: This goroutine leaks forever! It has no way to be stopped,
-	// and we have no way to wait for it to finish gracefully.
-
-	// TODO: Fix this lifecycle.
-	go func() {
-		for {
-			time.Sleep(10 * time.Millisecond) // Simulated ticker
-			c.evict()
-		}
-	}()
+	c.startOnce.Do(func() {
+		// The ticker is created before Start returns: a fake clock sees the waiter as
+		// soon as the loop exists, and Stop never races its creation.
+		ticker := c.clock.NewTicker(c.interval)
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			defer ticker.Stop()
+			for {
+				select {
+				case <-c.done:
+					return
+				case <-ticker.C():
+					c.evict()
+				}
+			}
+		}()
+	})
 }
 
+// Stop signals the loop to exit and blocks until it has. It is safe to call more
+// than once, and before Start.
 func (c *Cache) Stop() {
-	// TODO: Signal the goroutine to stop, and block until it actually returns.
+	c.stopOnce.Do(func() { close(c.done) })
+	c.wg.Wait()
 }
 
+// Evictions returns how many eviction passes have run.
+func (c *Cache) Evictions() int64 { return c.evictions.Load() }
+
 func (c *Cache) evict() {
 	// Simulated work
+	c.evictions.Add(1)
 }
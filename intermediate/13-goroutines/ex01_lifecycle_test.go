@@ -4,6 +4,8 @@ import (
 	"runtime"
 	"testing"
 	"time"
+
+	"go-playbook/basic/09-time/clock"
 )
 
 func TestCacheLifecycle(t *testing.T) {
@@ -31,3 +33,27 @@ func TestCacheLifecycle(t *testing.T) {
 		t.Fatalf("LEAK DETECTED: Expected %d goroutines after Stop(), got %d. The background loop did not exit.", initialRoutines, finalRoutines)
 	}
 }
+
+func TestCacheEvictsOnEveryTick(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewCacheWithClock(clk, 10*time.Second)
+	cache.Start()
+	defer cache.Stop()
+
+	clk.BlockUntil(1) // The ticker is registered.
+	for want := int64(1); want <= 3; want++ {
+		clk.Advance(10 * time.Second)
+		for cache.Evictions() < want {
+			runtime.Gosched() // The loop receives the tick asynchronously.
+		}
+	}
+
+	cache.Stop()
+	if clk.Waiters() != 0 {
+		t.Errorf("Expected Stop to release the ticker, %d waiters remain", clk.Waiters())
+	}
+	clk.Advance(time.Minute)
+	if got := cache.Evictions(); got != 3 {
+		t.Errorf("Expected no evictions after Stop, got %d", got)
+	}
+}
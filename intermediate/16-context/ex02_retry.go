@@ -18,7 +18,10 @@ package ctxexercises
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
+
+	"go-playbook/basic/09-time/clock"
 )
 
 var ErrServerDown = errors.New("500 internal server error")
@@ -27,11 +30,21 @@ func UnreliableAPI() (string, error) {
 	return "", ErrServerDown
 }
 
+const (
+	MaxAttempts = 5
+	RetryDelay  = 1 * time.Second
+)
+
+var ErrMaxRetries = errors.New("max retries exceeded")
+
 func FetchWithRetry(ctx context.Context) (string, error) {
-	// BUG: This loop sleeps and retries even if the context was already canceled!
-	// TODO: Replace time.Sleep with a select on time.After and ctx.Done().
+	return FetchWithRetryClock(ctx, clock.Real)
+}
 
-	for i := 0; i < 5; i++ {
+// FetchWithRetryClock is FetchWithRetry with the delays measured on clk.
+func FetchWithRetryClock(ctx context.Context, clk clock.Clock) (string, error) {
+	var lastErr error
+	for attempt := range MaxAttempts {
 		// Check context before even making the call
 		if err := ctx.Err(); err != nil {
 			return "", err
@@ -41,10 +54,20 @@ func FetchWithRetry(ctx context.Context) (string, error) {
 		if err == nil {
 			return res, nil
 		}
+		lastErr = err
+		if attempt == MaxAttempts-1 {
+			break // No point sleeping after the last attempt.
+		}
 
-		// Wait 1 second before retrying
-		time.Sleep(1 * time.Second)
+		// Wait before retrying, unless the caller gives up first.
+		timer := clk.NewTimer(RetryDelay)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		}
 	}
 
-	return "", errors.New("max retries exceeded")
+	return "", fmt.Errorf("%w: %w", ErrMaxRetries, lastErr)
 }
@@ -2,8 +2,11 @@ package ctxexercises
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"go-playbook/basic/09-time/clock"
 )
 
 func TestFetchWithRetryCancellation(t *testing.T) {
@@ -32,3 +35,34 @@ func TestFetchWithRetryCancellation(t *testing.T) {
 		t.Fatalf("FAILED: Retry loop ignored the context cancellation during sleep! It took %v", duration)
 	}
 }
+
+func TestFetchWithRetryClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := FetchWithRetryClock(context.Background(), clk)
+		errCh <- err
+	}()
+	for range MaxAttempts - 1 {
+		clk.BlockUntil(1) // Sleeping between attempts.
+		clk.Advance(RetryDelay)
+	}
+	if err := <-errCh; !errors.Is(err, ErrMaxRetries) || !errors.Is(err, ErrServerDown) {
+		t.Fatalf("Expected ErrMaxRetries wrapping ErrServerDown, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := FetchWithRetryClock(ctx, clk)
+		errCh <- err
+	}()
+	clk.BlockUntil(1)
+	cancel() // No time passes: the loop must react to the context alone.
+	if err := <-errCh; err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if n := clk.Waiters(); n != 0 {
+		t.Errorf("Expected the retry timer to be stopped, %d waiters remain", n)
+	}
+}